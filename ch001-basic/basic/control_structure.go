@@ -2,42 +2,50 @@ package basic
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
 )
 
+func init() {
+	Register(Demo{Name: "if", Chapter: "ch001-basic", Description: "if...else if...else条件语句", RunRand: IfDemo})
+	Register(Demo{Name: "switch", Chapter: "ch001-basic", Description: "switch选择语句和fallthrough", RunRand: SwitchDemo})
+	Register(Demo{Name: "for", Chapter: "ch001-basic", Description: "for循环以及用for实现while", Run: ForDemo})
+}
+
 // if...else if...else条件语句
-func IfDemo() {
+// 随机数由调用方传入的rnd生成，固定种子就能稳定复现某个分支
+func IfDemo(w io.Writer, rnd *rand.Rand) {
 	min, max := 10, 60
 	// 模拟随机生成[min, max)
-	num := rand.Intn(max-min) + min
-	fmt.Println(num)
+	num := rnd.Intn(max-min) + min
+	fmt.Fprintln(w, num)
 	if num >= 10 && num < 20 {
-		fmt.Println("10<=num<20")
+		fmt.Fprintln(w, "10<=num<20")
 	} else if num >= 20 && num < 30 {
-		fmt.Println("20<=num<30")
+		fmt.Fprintln(w, "20<=num<30")
 	} else if num >= 30 && num < 40 {
-		fmt.Println("30<=num<40")
+		fmt.Fprintln(w, "30<=num<40")
 	} else if num >= 40 && num < 50 {
-		fmt.Println("40<=num<50")
+		fmt.Fprintln(w, "40<=num<50")
 	} else {
-		fmt.Println("50<=num<60")
+		fmt.Fprintln(w, "50<=num<60")
 	}
 }
 
 // switch选择语句
-func SwitchDemo() {
+func SwitchDemo(w io.Writer, rnd *rand.Rand) {
 	min, max := 10, 60
-	switch n := rand.Intn(max-min) + min; {
+	switch n := rnd.Intn(max-min) + min; {
 	case n >= 10 && n < 20:
-		fmt.Println("10<=n<20")
+		fmt.Fprintln(w, "10<=n<20")
 	case n >= 20 && n < 30:
-		fmt.Println("20<=n<30")
+		fmt.Fprintln(w, "20<=n<30")
 	case n >= 30 && n < 40:
-		fmt.Println("30<=n<40")
+		fmt.Fprintln(w, "30<=n<40")
 	case n >= 40 && n < 50:
-		fmt.Println("40<=n<50")
+		fmt.Fprintln(w, "40<=n<50")
 	default:
-		fmt.Println("50<=n<60")
+		fmt.Fprintln(w, "50<=n<60")
 	}
 
 	// fallthrough可以执行下一个紧跟的case
@@ -47,18 +55,18 @@ func SwitchDemo() {
 	case 1:
 		fallthrough
 	default:
-		fmt.Println(1)
+		fmt.Fprintln(w, 1)
 	}
 }
 
-func ForDemo() {
+func ForDemo(w io.Writer) {
 	// 计算min~max之间所有数字之和
 	min, max := 0, 100
 	sum := 0
 	for i := min; i <= max; i++ {
 		sum += i
 	}
-	fmt.Println("sum:", sum)
+	fmt.Fprintln(w, "sum:", sum)
 
 	// Go语言中没有while循环，但可以用for达到while的效果
 	min, max, sum, i := 0, 100, 0, min
@@ -66,5 +74,5 @@ func ForDemo() {
 		sum += i
 		i++
 	}
-	fmt.Println("sum:", sum)
+	fmt.Fprintln(w, "sum:", sum)
 }
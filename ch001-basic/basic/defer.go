@@ -1,38 +1,71 @@
 package basic
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+
+	"go-practice/ch001-basic/fileutil"
 )
 
-func DeferDemo() {
-	filename := "/tmp/tmp.txt"
-	ReadFile(filename)
-	MultiDeferDemo()
+func init() {
+	Register(Demo{Name: "defer", Chapter: "ch001-basic", Description: "defer语句及其执行顺序", Run: DeferDemo})
+}
+
+// DeferDemo 读取的文件由演示自己在临时目录中创建，临时目录同样通过defer删除，输出不依赖运行环境
+func DeferDemo(w io.Writer) {
+	dir, err := os.MkdirTemp("", "defer-demo-*")
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tmp.txt")
+	// 文件还没有创建，输出file is not exists
+	ReadFile(w, filename)
+	if err := fileutil.WriteAtomic(filename, []byte("hello defer"), 0o644); err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	ReadFile(w, filename)
+	MultiDeferDemo(w)
 }
 
 /**
  * defer关键字用于修饰一个函数或者方法，使得该函数或方法在返回前才会执行，也就是说会延迟但又保证一定执行
  * defer语句常被用于成对的操作，如文件的打开和关闭，加锁和释放锁，连接的建立和断开等
  * 不管多么复杂的操作，都可以保证资源被正确的释放
+ * fileutil包在此基础上提供了按行/按块读取、大小限制、原子写入和文件锁，错误可以用errors.Is区分文件不存在、没有权限和文件过大
  */
-func ReadFile(filename string) ([]byte, error) {
-	f, err := os.Open(filename)
+func ReadFile(w io.Writer, filename string) ([]byte, error) {
+	f, err := fileutil.Open(filename)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("file is not exists")
+		switch {
+		case errors.Is(err, fileutil.ErrNotExist):
+			fmt.Fprintln(w, "file is not exists")
+		case errors.Is(err, fileutil.ErrPermission):
+			fmt.Fprintln(w, "permission denied")
 		}
 		return nil, err
 	}
 	defer f.Close()
-	contentBytes, err := io.ReadAll(f)
+	// 限制读取的大小，防止误读一个很大的文件耗尽内存；需要处理大文件时使用fileutil.ReadLines按行读取
+	contentBytes, err := fileutil.ReadAllFrom(context.Background(), f, fileutil.Options{MaxSize: maxReadFileSize})
+	if errors.Is(err, fileutil.ErrTooLarge) {
+		fmt.Fprintf(w, "file is larger than %d bytes\n", maxReadFileSize)
+	}
 	if err == nil {
-		fmt.Println(string(contentBytes))
+		fmt.Fprintln(w, string(contentBytes))
 	}
 	return contentBytes, err
 }
 
+// maxReadFileSize ReadFile最多读取的字节数
+const maxReadFileSize = 1 << 20
+
 /**
  * 多个defer的定义与执行类似于栈的操作：先进后出，最先定义的最后执行
  * 输出结果如下:
@@ -41,19 +74,19 @@ func ReadFile(filename string) ([]byte, error) {
  * defer_func_2[x=>2]
  * defer_func_1[x=>3]
  */
-func MultiDeferDemo() {
+func MultiDeferDemo(w io.Writer) {
 	x := 0
 	defer func() {
 		x++
-		fmt.Printf("defer_func_1[x=>%d]\n", x)
+		fmt.Fprintf(w, "defer_func_1[x=>%d]\n", x)
 	}()
 	defer func() {
 		x++
-		fmt.Printf("defer_func_2[x=>%d]\n", x)
+		fmt.Fprintf(w, "defer_func_2[x=>%d]\n", x)
 	}()
 	defer func() {
 		x++
-		fmt.Printf("defer_func_3[x=>%d]\n", x)
+		fmt.Fprintf(w, "defer_func_3[x=>%d]\n", x)
 	}()
-	fmt.Printf("main_func[x=>%d]\n", x)
+	fmt.Fprintf(w, "main_func[x=>%d]\n", x)
 }
@@ -1,19 +1,32 @@
 package basic
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+
+	"go-practice/ch001-basic/db"
+	"go-practice/ch001-basic/errs"
+	"go-practice/ch001-basic/validate"
 )
 
-func ErrorDemo() {
-	errorStrToIntTest()
-	errorSumTest()
-	commonErrorSumTest()
+func init() {
+	Register(Demo{Name: "error", Chapter: "ch001-basic", Description: "error接口和自定义error", Run: ErrorDemo})
+	Register(Demo{Name: "panic", Chapter: "ch001-basic", Description: "panic异常和recover恢复", Run: PanicDemo})
+}
+
+func ErrorDemo(w io.Writer) {
+	errorStrToIntTest(w)
+	errorSumTest(w)
+	commonErrorSumTest(w)
+	codedErrorSumTest(w)
 }
 
-func PanicDemo() {
-	connDB("", "root", "123456")
+func PanicDemo(w io.Writer) {
+	connDB(w, "", "root", "123456")
+	connDBConfig(w, "", "root", "")
 }
 
 /**
@@ -32,32 +45,43 @@ func PanicDemo() {
  * error接口用于当方法或者函数执行遇到错误时进行返回，而且是第二个返回值
  * 通过这种方式，可以让调用者自己根据错误信息决定如何进行下一步处理
  */
-func errorStrToIntTest() {
+func errorStrToIntTest(w io.Writer) {
 	i, err := strconv.Atoi("a")
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(w, err)
 	} else {
-		fmt.Println(i)
+		fmt.Fprintln(w, i)
 	}
 }
 
 /**
  * error工厂函数: errors.New(string)
  * 自定义函数也可以返回错误信息给调用者
+ * 但errors.New("a或者b不能为负数")只有一句话，调用者不知道到底是a还是b有问题
+ * 所以errorSum使用validate.Validator分别检查每个参数，返回所有参数的错误
  */
 func errorSum(a, b int) (int, error) {
-	if a < 0 || b < 0 {
-		return 0, errors.New("a或者b不能为负数")
-	} else {
-		return a + b, nil
+	var v validate.Validator
+	v.Check(a >= 0, "a", "不能为负数")
+	v.Check(b >= 0, "b", "不能为负数")
+	if err := v.Err(); err != nil {
+		return 0, err
 	}
+	return a + b, nil
 }
-func errorSumTest() {
+func errorSumTest(w io.Writer) {
 	sum, err := errorSum(-1, 2)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(w, err)
 	} else {
-		fmt.Println(sum)
+		fmt.Fprintln(w, sum)
+	}
+	// a和b都不合法时两个错误都会返回，也可以输出为JSON
+	_, err = errorSum(-1, -2)
+	var verrs validate.Errors
+	if errors.As(err, &verrs) {
+		b, _ := json.Marshal(verrs)
+		fmt.Fprintf(w, "%v\nfields:%v\njson:%s\n", err, verrs.Fields(), b)
 	}
 }
 
@@ -92,15 +116,41 @@ func commonErrorSum(a, b int) (int, error) {
  * 有了自定义error，并且可以包含更多的错误信息后，就可以使用这些信息了
  * 需要先把返回的error接口转换为自定义的错误类型(使用类型断言)
  */
-func commonErrorSumTest() {
+func commonErrorSumTest(w io.Writer) {
 	sum, err := commonErrorSum(-1, 2)
 	if cm, ok := err.(*commonError); ok {
-		fmt.Printf("errorCode:%d, errorMsg:%s\n", cm.errorCode, cm.errorMsg)
+		fmt.Fprintf(w, "errorCode:%d, errorMsg:%s\n", cm.errorCode, cm.errorMsg)
 	} else {
-		fmt.Println(sum)
+		fmt.Fprintln(w, sum)
 	}
 }
 
+/**
+ * commonError的不足：
+ * 1. 不能包装底层错误，调用者用fmt.Errorf("...: %w", err)再包装一层后，类型断言就失效了
+ * 2. 只能通过类型断言判断错误码，不能用errors.Is/errors.As沿着错误链查找
+ * errs包在commonError的基础上补全了这些能力，并且错误码可以映射为HTTP状态码和面向用户的提示信息
+ */
+func codedErrorSum(a, b int) (int, error) {
+	if a < 0 || b < 0 {
+		return 0, errs.New(errs.InvalidArgument, "a或者b不能为负数").With("a", a).With("b", b)
+	}
+	return a + b, nil
+}
+
+func codedErrorSumTest(w io.Writer) {
+	_, err := codedErrorSum(-1, 2)
+	// 调用者再包装一层，错误码和字段仍然可以取出来
+	err = fmt.Errorf("计算失败: %w", err)
+	fmt.Fprintln(w, err)
+	fmt.Fprintln(w, "errors.Is(err, errs.InvalidArgument):", errors.Is(err, errs.InvalidArgument))
+	var e *errs.Error
+	if errors.As(err, &e) {
+		fmt.Fprintf(w, "code:%d(%v), fields:%v\n", e.Code, e.Code, e.Fields())
+	}
+	fmt.Fprintf(w, "httpStatus:%d, userMessage:%s\n", errs.HTTPStatus(err), errs.UserMessage(err))
+}
+
 /**
  * Panic异常
  * Go语言是一门静态的强类型语言，很多问题都尽可能在编译时捕获，但是有一些只能在运行时检查
@@ -118,11 +168,12 @@ func commonErrorSumTest() {
  * 可以通过内置的recover函数恢复panic异常
  * 因为在程序panic异常崩溃的时，只有被defer修饰的函数才能被执行，所以recover函数要结合defer关键字使用才能生效
  * [defer关键字 + 匿名函数 + recover函数]从panic异常中恢复
+ * 这种写法的通用版本：协程使用ch002-concurrent/concurrent包的Go启动，web服务使用ch003-web/web包的Recover中间件
  */
-func connDB(host, username, password string) {
+func connDB(w io.Writer, host, username, password string) {
 	defer func() {
 		if p := recover(); p != nil {
-			fmt.Println(p)
+			fmt.Fprintln(w, p)
 			//...
 		}
 	}()
@@ -131,3 +182,18 @@ func connDB(host, username, password string) {
 	}
 	//...
 }
+
+/**
+ * connDB用panic报告配置错误，并且遇到一个问题就停止检查，调用者一次只能看到一个问题
+ * 配置错误是可以预期的，应该用error返回：db.Config.Validate一次检查所有配置项，用errors.Join返回所有问题
+ * 打印配置时密码显示为xxxxx，不会出现在日志中
+ */
+func connDBConfig(w io.Writer, host, username, password string) {
+	cfg := db.Config{Driver: db.MemDriverName, Host: host, Username: username, Password: password}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(w, err)
+		fmt.Fprintln(w, "errors.Is(err, db.ErrInvalidConfig):", errors.Is(err, db.ErrInvalidConfig))
+	}
+	cfg.Host, cfg.Password = "localhost", "123456"
+	fmt.Fprintln(w, cfg, cfg.Validate())
+}
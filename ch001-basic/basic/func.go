@@ -1,29 +1,39 @@
 package basic
 
 import (
-	"errors"
 	"fmt"
+	"io"
+
+	"go-practice/ch001-basic/validate"
 )
 
-func FuncDemo() {
-	fmt.Println("FuncDemo1:", FuncDemo1(1, 2))
+func init() {
+	Register(Demo{Name: "func", Chapter: "ch001-basic", Description: "函数、多值返回、可变参数、匿名函数和闭包", Run: FuncDemo})
+}
+
+func FuncDemo(w io.Writer) {
+	fmt.Fprintln(w, "FuncDemo1:", FuncDemo1(1, 2))
 
 	sum, err := FuncDemo2(2, 3)
 	if err == nil {
-		fmt.Println("FuncDemo2:", sum)
+		fmt.Fprintln(w, "FuncDemo2:", sum)
 	}
 
 	sum1, err1 := FuncDemo3(3, 4)
 	if err1 == nil {
-		fmt.Println("FuncDemo3:", sum1)
+		fmt.Fprintln(w, "FuncDemo3:", sum1)
+	}
+	// 参数不合法时，错误中包含每个不合法的参数
+	if _, err := FuncDemo3(-3, -4); err != nil {
+		fmt.Fprintf(w, "FuncDemo3 error:%q\n", err)
 	}
 
 	sum2 := FuncDemo4(1, 2, 3, 4, 5)
-	fmt.Println("FuncDemo4:", sum2)
+	fmt.Fprintln(w, "FuncDemo4:", sum2)
 
-	FuncDemo5(5, 6)
+	FuncDemo5(w, 5, 6)
 
-	FuncDemo6()
+	FuncDemo6(w)
 }
 
 /**
@@ -41,8 +51,8 @@ func FuncDemo1(a, b int) int {
 // 多值返回函数，Go语言的函数可以返回多个值
 // 在Go语言标准库中有很多这样的函数，第一个值返回函数的结果，第二个值返回函数出错的信息，这就是多值返回
 func FuncDemo2(a, b int) (int, error) {
-	if a < 0 || b < 0 {
-		return 0, errors.New("参数必须是正数")
+	if err := checkPositive(a, b); err != nil {
+		return 0, err
 	}
 	return a + b, nil
 }
@@ -50,14 +60,22 @@ func FuncDemo2(a, b int) (int, error) {
 // 命名返回值
 // 可以为每个返回值起一个名字，这个名字可以像参数一样在函数体内使用
 func FuncDemo3(a, b int) (sum int, err error) {
-	if a < 0 || b < 0 {
-		return 0, errors.New("参数必须是正数")
+	if err = checkPositive(a, b); err != nil {
+		return 0, err
 	}
 	sum = a + b
 	err = nil
 	return
 }
 
+// checkPositive 分别检查a和b，返回所有不合法参数的错误
+func checkPositive(a, b int) error {
+	var v validate.Validator
+	v.Check(a >= 0, "a", "参数必须是正数")
+	v.Check(b >= 0, "b", "参数必须是正数")
+	return v.Err()
+}
+
 // 可变参数，即函数的参数数量是可变的
 // 同一个函数，可以不传参数，也可以传递一个参数，也可以传递多个参数，这种函数就是具有可变参数的函数
 // 例如最常见的fmt.Println函数：func Println(a ...interface{}) (n int, err error)
@@ -71,23 +89,23 @@ func FuncDemo4(params ...int) int {
 }
 
 // 匿名函数
-func FuncDemo5(a, b int) {
+func FuncDemo5(w io.Writer, a, b int) {
 	// sum对应的值就是一个匿名函数，这里的sum只是一个函数类型的变量，并不是函数的名字
 	sum := func(x, y int) int {
 		return x + y
 	}
-	fmt.Println("FuncDemo5:", sum(a, b))
+	fmt.Fprintln(w, "FuncDemo5:", sum(a, b))
 }
 
 // 闭包
 // 有了匿名函数，就可以在函数中再定义函数（函数嵌套），定义的这个匿名函数，也可以称为内部函数
 // 在函数内定义的内部函数，可以使用外部函数的变量等，这种方式称为闭包
-func FuncDemo6() {
+func FuncDemo6(w io.Writer) {
 	cl := closure()
 	// 每调用一次cl()，i的值就会加1
-	fmt.Println("FuncDemo6:", cl())
-	fmt.Println("FuncDemo6:", cl())
-	fmt.Println("FuncDemo6:", cl())
+	fmt.Fprintln(w, "FuncDemo6:", cl())
+	fmt.Fprintln(w, "FuncDemo6:", cl())
+	fmt.Fprintln(w, "FuncDemo6:", cl())
 }
 
 // 得益于闭包函数闭包的能力，自定义closure函数，可以返回一个匿名函数并且持有外部函数closure的变量i
@@ -0,0 +1,82 @@
+package basic
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 修改了演示代码并确认输出无误后，使用 go test ./ch001-basic/basic -update 重新生成golden文件
+var update = flag.Bool("update", false, "更新testdata下的golden文件")
+
+// goldenSeed 依赖随机数的演示统一使用的种子，修改后需要重新生成golden文件
+const goldenSeed = 1
+
+// 不单独注册的演示函数，golden文件以函数名命名
+var goldenCases = []struct {
+	name string
+	run  func(w io.Writer)
+}{
+	{"ArrayDemo", ArrayDemo},
+	{"SliceDemo", SliceDemo},
+	{"StringDemo", StringDemo},
+	{"MultiDeferDemo", MultiDeferDemo},
+	{"FuncDemo6", FuncDemo6},
+	{"StructExtendsDemo", StructExtendsDemo},
+	{"reflectTypeDemo", reflectTypeDemo},
+	{"reflectValueDemo", reflectValueDemo},
+	// 种子6生成的随机数为58，用来覆盖50<=n<60的default分支
+	{"IfDemo-seed6", func(w io.Writer) { IfDemo(w, rand.New(rand.NewSource(6))) }},
+	{"SwitchDemo-seed6", func(w io.Writer) { SwitchDemo(w, rand.New(rand.NewSource(6))) }},
+}
+
+// 输出不确定的演示不做golden测试：collection中MapDemo遍历map的顺序是随机的
+var goldenSkipped = map[string]bool{
+	"collection": true,
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tc.run(&buf)
+			assertGolden(t, tc.name, buf.Bytes())
+		})
+	}
+}
+
+// 注册表中的每个演示都做golden测试，golden文件以演示名称命名，新注册的演示需要先用-update生成
+func TestGoldenRegistry(t *testing.T) {
+	for _, d := range Demos() {
+		if goldenSkipped[d.Name] {
+			continue
+		}
+		t.Run(d.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			d.Exec(&buf, goldenSeed)
+			assertGolden(t, "demo-"+d.Name, buf.Bytes())
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取golden文件失败(可使用-update生成): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s输出与golden文件不一致\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
@@ -1,6 +1,13 @@
 package basic
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(Demo{Name: "interface", Chapter: "ch001-basic", Description: "接口及其实现", Run: InterfaceDemo})
+}
 
 /**
  * 接口是和调用方的一种约定，不用和具体的实现细节绑定在一起
@@ -35,20 +42,20 @@ func (m *Apple) call() string {
  * 以值类型接收者实现接口的时候，不管是类型本身，还是该类型的指针类型，都实现了该接口
  * 以指针类型接收者实现接口的时候，只有对应的指针类型才被认为实现了该接口
  */
-func InterfaceDemo() {
+func InterfaceDemo(w io.Writer) {
 	var h1 Mobile = Huawei{}
-	fmt.Println(h1.call())
+	fmt.Fprintln(w, h1.call())
 	var h2 Mobile = new(Huawei)
-	fmt.Println(h2.call())
+	fmt.Fprintln(w, h2.call())
 
 	var x1 Mobile = Xiaomi{}
-	fmt.Println(x1.call())
+	fmt.Fprintln(w, x1.call())
 	var x2 Mobile = new(Xiaomi)
-	fmt.Println(x2.call())
+	fmt.Fprintln(w, x2.call())
 
 	// 只有Apple的指针类型实现了该接口
 	// var a1 Mobile = Apple{}
-	// fmt.Println(a1.call())
+	// fmt.Fprintln(w, a1.call())
 	var a2 Mobile = new(Apple)
-	fmt.Println(a2.call())
+	fmt.Fprintln(w, a2.call())
 }
@@ -2,14 +2,19 @@ package basic
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 )
 
-func NewMakeDemo() {
-	//varTest()
-	newTest()
-	//varInitTest()
-	makeTest()
+func init() {
+	Register(Demo{Name: "new-make", Chapter: "ch001-basic", Description: "new和make的区别", Run: NewMakeDemo})
+}
+
+func NewMakeDemo(w io.Writer) {
+	//varTest(w)
+	newTest(w)
+	//varInitTest(w)
+	makeTest(w)
 }
 
 /**
@@ -18,19 +23,19 @@ func NewMakeDemo() {
  * 对于指针类型，声明后默认是零值nil，该变量没有指向的内存空间，如果进行赋值操作就会引发nil指针错误
  * 总结：如果要对一个变量赋值，这个变量必须有对应分配好的内存，这样才能对这块内存操作完成赋值目的
  */
-func varTest() {
+func varTest(w io.Writer) {
 	var str string
 	str = "hello str"
-	fmt.Println(str)
+	fmt.Fprintln(w, str)
 
 	var strP *string
 	// panic: runtime error: invalid memory address or nil pointer dereference
 	*strP = "hello strP"
-	fmt.Println(*strP)
+	fmt.Fprintln(w, *strP)
 
 	var strP1 *string = &str
 	*strP1 = "hello strP1"
-	fmt.Println(*strP1)
+	fmt.Fprintln(w, *strP1)
 }
 
 /**
@@ -38,14 +43,14 @@ func varTest() {
  * new的作用就是根据传入的类型申请一块内存，然后返回指向这块内存的指针，指针指向的数据就是该类型的零值
  * 内置new函数定义：func new(Type) *Type
  */
-func newTest() {
+func newTest(w io.Writer) {
 	var strP *string
 	strP = new(string)
 	// 打印空字符串，也就是string的零值
-	fmt.Println(*strP)
+	fmt.Fprintln(w, *strP)
 	// new已为指针变量分配了内存，可以直接赋值
 	*strP = "hello strP"
-	fmt.Println(*strP)
+	fmt.Fprintln(w, *strP)
 }
 
 /**
@@ -60,17 +65,17 @@ type Student struct {
  * 变量初始化（不初始化的变量的值为该变量类型的零值）
  * 当声明一个类型的变量时还对这个变量进行了赋值，这个修改变量值的过程称为变量的初始化
  */
-func varInitTest() {
+func varInitTest(w io.Writer) {
 	// 字面量初始化，基础类型和复合类型都可以通过这种方式进行初始化
 	p1 := Student{name: "student1", age: 19}
 	// 指针变量初始化
 	p2 := newStudent("student2", 20)
 	// 值变量
-	fmt.Printf("p1 type: %T\n", p1)
-	fmt.Println("p1 type:", reflect.TypeOf(p1))
+	fmt.Fprintf(w, "p1 type: %T\n", p1)
+	fmt.Fprintln(w, "p1 type:", reflect.TypeOf(p1))
 	// 指针变量
-	fmt.Printf("p2 type: %T\n", p2)
-	fmt.Println("p2 type:", reflect.TypeOf(p2))
+	fmt.Fprintf(w, "p2 type: %T\n", p2)
+	fmt.Fprintln(w, "p2 type:", reflect.TypeOf(p2))
 }
 
 // 通过封装函数初始化变量（工厂函数）
@@ -85,20 +90,20 @@ func newStudent(name string, age int32) *Student {
  * make返回引用类型
  * make函数只用于slice、map、chan这三种内置类型的创建和初始化
  */
-func makeTest() {
-	makeSliceTest()
-	makeMapTest()
+func makeTest(w io.Writer) {
+	makeSliceTest(w)
+	makeMapTest(w)
 }
 
-func makeSliceTest() {
+func makeSliceTest(w io.Writer) {
 	slice := make([]string, 3, 3)
 	slice[0] = "a"
 	slice[1] = "b"
 	slice[2] = "c"
-	fmt.Println(slice)
+	fmt.Fprintln(w, slice)
 }
 
-func makeMapTest() {
+func makeMapTest(w io.Writer) {
 	// mapVar := map[string]string{"beijing":"北京", "shanghai":"上海"}
 	var m map[string]string
 	m = make(map[string]string)
@@ -108,8 +113,8 @@ func makeMapTest() {
 	m["guangzhou"] = "广州"
 	m["shenzhen"] = "深圳"
 	m["hangzhou"] = "杭州"
-	fmt.Println("m type:", reflect.TypeOf(m))
-	fmt.Println(m)
+	fmt.Fprintln(w, "m type:", reflect.TypeOf(m))
+	fmt.Fprintln(w, m)
 }
 
 // 函数new和make的区别？
@@ -2,22 +2,27 @@ package basic
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 )
 
+func init() {
+	Register(Demo{Name: "reflect", Chapter: "ch001-basic", Description: "反射获取类型信息和值信息", Run: ReflectDemo})
+}
+
 type User struct {
 	Name   string `json:"name" xml:"name"`
 	Age    int16  `json:"age" xml:"age"`
 	Gender string `json:"gender" xml:"gender"`
 }
 
-func ReflectDemo() {
-	reflectTypeDemo()
-	reflectValueDemo()
+func ReflectDemo(w io.Writer) {
+	reflectTypeDemo(w)
+	reflectValueDemo(w)
 }
 
 // 通过反射获取类型信息
-func reflectTypeDemo() {
+func reflectTypeDemo(w io.Writer) {
 	user := User{Name: "Tom", Age: 20, Gender: "male"}
 	// 使用reflect.TypeOf()函数可以获得任意值的类型对象(reflect.Type)
 	// 程序通过类型对象可以访问任意值的类型信息
@@ -25,7 +30,7 @@ func reflectTypeDemo() {
 	// reflect.Type.Name()
 	// reflect.Type.Kind()
 	// reflect.Type.NumField()
-	fmt.Printf("[reflect.Type.Name]:%v\n[reflect.Type.Kind]:%v\n[reflect.Type.NumField]:%v\n", t.Name(), t.Kind(), t.NumField())
+	fmt.Fprintf(w, "[reflect.Type.Name]:%v\n[reflect.Type.Kind]:%v\n[reflect.Type.NumField]:%v\n", t.Name(), t.Kind(), t.NumField())
 	// Output:
 	// [reflect.Type.Name]:User
 	// [reflect.Type.Kind]:struct
@@ -36,20 +41,20 @@ func reflectTypeDemo() {
 		// 获取每个成员的结构体字段类型
 		fieldType := t.Field(i)
 		// 输出成员名和tag
-		fmt.Printf("name:%v, tag:%v\n", fieldType.Name, fieldType.Tag)
+		fmt.Fprintf(w, "name:%v, tag:%v\n", fieldType.Name, fieldType.Tag)
 		// 解析Tag
-		fmt.Printf("json:%v, xml:%v\n", fieldType.Tag.Get("json"), fieldType.Tag.Get("xml"))
+		fmt.Fprintf(w, "json:%v, xml:%v\n", fieldType.Tag.Get("json"), fieldType.Tag.Get("xml"))
 	}
 
 	// 通过字段名，找到字段类型信息
 	if fieldType, ok := t.FieldByName("Age"); ok {
 		// 解析Tag
-		fmt.Printf("[Age] => json:%v, xml:%v\n", fieldType.Tag.Get("json"), fieldType.Tag.Get("xml"))
+		fmt.Fprintf(w, "[Age] => json:%v, xml:%v\n", fieldType.Tag.Get("json"), fieldType.Tag.Get("xml"))
 	}
 }
 
 // 通过反射获取值信息
-func reflectValueDemo() {
+func reflectValueDemo(w io.Writer) {
 	// 反射不仅可以获取值的类型信息，还可以通过reflect.Value动态地获取或者设置变量的值
 	// 变量、interface{}和reflect.Value是可以相互转换的
 	user := User{Name: "Tom", Age: 20, Gender: "male"}
@@ -57,14 +62,14 @@ func reflectValueDemo() {
 	v1 := reflect.ValueOf(user)
 	// 从反射对象到接口变量，并通过类型断言转换
 	u1 := v1.Interface().(User)
-	fmt.Println(v1, v1.FieldByName("Name").String())
-	fmt.Println(u1)
+	fmt.Fprintln(w, v1, v1.FieldByName("Name").String())
+	fmt.Fprintln(w, u1)
 
 	// 通过反射修改变量的值，需要传递变量的指针创建反射对象，保证其值是可写的
 	v2 := reflect.ValueOf(&user)
 	v3 := v2.Elem()
 	v3.FieldByName("Name").SetString("Mike")
-	fmt.Println(user)
+	fmt.Fprintln(w, user)
 }
 
 /**
@@ -0,0 +1,80 @@
+package basic
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+/**
+ * 演示注册表
+ * 每个演示在所在文件的init函数中通过Register注册自己，包括名称、所属章节和简要说明
+ * 这样main函数不需要再通过注释/取消注释来选择要运行的演示，而是可以通过命令行按名称运行
+ */
+type Demo struct {
+	Name        string            // 演示名称，命令行中使用，如run collection
+	Chapter     string            // 所属章节
+	Description string            // 简要说明
+	Run         func(w io.Writer) // 演示入口，所有输出都写入w
+	// 依赖随机数的演示使用RunRand代替Run，运行时会传入按种子创建的*rand.Rand，
+	// 这样同一个种子总是得到相同的分支，Run和RunRand只能设置一个
+	RunRand func(w io.Writer, rnd *rand.Rand)
+}
+
+// Exec 运行演示，每次运行都用seed新建一个*rand.Rand，演示之间互不影响
+func (d Demo) Exec(w io.Writer, seed int64) {
+	if d.RunRand != nil {
+		d.RunRand(w, rand.New(rand.NewSource(seed)))
+		return
+	}
+	d.Run(w)
+}
+
+var (
+	demosMu sync.RWMutex
+	demos   []Demo
+)
+
+// Register 注册一个演示，名称重复或Run/RunRand设置有误会panic，属于编程错误，应在init阶段暴露出来
+func Register(d Demo) {
+	demosMu.Lock()
+	defer demosMu.Unlock()
+	if d.Name == "" || (d.Run == nil) == (d.RunRand == nil) {
+		panic("basic: Register需要非空的Name，并且Run和RunRand只能设置一个")
+	}
+	for _, exist := range demos {
+		if exist.Name == d.Name {
+			panic(fmt.Sprintf("basic: 演示%q重复注册", d.Name))
+		}
+	}
+	demos = append(demos, d)
+}
+
+// Demos 按章节、名称排序后返回所有演示
+// 注册顺序取决于init的执行顺序，也就是文件名的顺序，排序后list和run --all的顺序不会因为增删文件而变化
+func Demos() []Demo {
+	demosMu.RLock()
+	defer demosMu.RUnlock()
+	res := append([]Demo(nil), demos...)
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Chapter != res[j].Chapter {
+			return res[i].Chapter < res[j].Chapter
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Lookup 按名称查找演示
+func Lookup(name string) (Demo, bool) {
+	demosMu.RLock()
+	defer demosMu.RUnlock()
+	for _, d := range demos {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Demo{}, false
+}
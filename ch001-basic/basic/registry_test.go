@@ -0,0 +1,16 @@
+package basic
+
+import "testing"
+
+func TestDemosSorted(t *testing.T) {
+	list := Demos()
+	if len(list) == 0 {
+		t.Fatal("没有注册任何演示")
+	}
+	for i := 1; i < len(list); i++ {
+		prev, cur := list[i-1], list[i]
+		if prev.Chapter > cur.Chapter || (prev.Chapter == cur.Chapter && prev.Name >= cur.Name) {
+			t.Errorf("%s/%s排在%s/%s之前", prev.Chapter, prev.Name, cur.Chapter, cur.Name)
+		}
+	}
+}
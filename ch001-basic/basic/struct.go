@@ -1,6 +1,13 @@
 package basic
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(Demo{Name: "struct", Chapter: "ch001-basic", Description: "结构体、工厂函数和组合", Run: StructDemo})
+}
 
 /**
  * 结构体是一种聚合类型，里面可以包含任意类型的值，这些值就是我们定义的结构体的成员(也称为字段)
@@ -32,7 +39,7 @@ func NewPerson(name string, age uint, addr address) *Person {
 	return &Person{name: name, age: age, addr: addr}
 }
 
-func StructDemo() {
+func StructDemo(w io.Writer) {
 	p1 := Person{name: "Tom", age: 25, addr: address{province: "浙江", city: "杭州"}}
 	p2 := Person{
 		name: "Lina",
@@ -43,11 +50,11 @@ func StructDemo() {
 		},
 	}
 	p3 := NewPerson("Pony", 30, address{province: "山东", city: "济南"})
-	fmt.Println(p1.name, p1.age, p1.addr.province, p1.addr.city)
-	fmt.Println(p2.name, p2.age, p2.addr.province, p2.addr.city)
-	fmt.Println(p3.name, p3.age, p3.addr.province, p3.addr.city)
+	fmt.Fprintln(w, p1.name, p1.age, p1.addr.province, p1.addr.city)
+	fmt.Fprintln(w, p2.name, p2.age, p2.addr.province, p2.addr.city)
+	fmt.Fprintln(w, p3.name, p3.age, p3.addr.province, p3.addr.city)
 	// 组合代替继承
-	StructExtendsDemo()
+	StructExtendsDemo(w)
 }
 
 /**
@@ -65,8 +72,8 @@ type person struct {
 	address
 }
 
-func StructExtendsDemo() {
+func StructExtendsDemo(w io.Writer) {
 	p := person{name: "Mike", age: 26, address: address{province: "北京", city: "北京"}}
-	fmt.Println(p)
-	fmt.Println(p.name, p.age, p.province, p.city)
+	fmt.Fprintln(w, p)
+	fmt.Fprintln(w, p.name, p.age, p.province, p.city)
 }
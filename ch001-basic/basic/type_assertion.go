@@ -1,6 +1,13 @@
 package basic
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(Demo{Name: "type-assertion", Chapter: "ch001-basic", Description: "类型断言", Run: TypeAssertionDemo})
+}
 
 /**
  * 类型断言
@@ -40,13 +47,13 @@ func (addr address1) String() string {
 }
 
 // person1和address1类型都实现了Stringer接口
-func TypeAssertionDemo() {
+func TypeAssertionDemo(w io.Writer) {
 	var s fmt.Stringer
 	p1 := NewPerson1("Tom", 20, address1{province: "广东", city: "深圳"})
 	s = p1
 	// 判断接口s的值是否是*person1类型，这就是类型断言
 	p2 := s.(*person1)
-	fmt.Println(p2)
+	fmt.Fprintln(w, p2)
 	/**
 	 * 类型断言的多值返回
 	 * address1也实现了Stringer接口，如果对s进行address1类型断言，就会抛出异常信息
@@ -55,11 +62,11 @@ func TypeAssertionDemo() {
 	 */
 	// a := s.(address1)
 	// panic: interface conversion: fmt.Stringer is *basic.person1, not basic.address1
-	// fmt.Println(a)
+	// fmt.Fprintln(w, a)
 	a, ok := s.(address1)
 	if ok {
-		fmt.Println(a)
+		fmt.Fprintln(w, a)
 	} else {
-		fmt.Println("s不是一个address1类型的值")
+		fmt.Fprintln(w, "s不是一个address1类型的值")
 	}
 }
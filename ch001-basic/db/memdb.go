@@ -0,0 +1,178 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemDriverName 内存驱动注册到database/sql的名称
+const MemDriverName = "memdb"
+
+// Mem 注册为memdb的内存驱动，例如db.Open(ctx, db.Config{Driver: db.MemDriverName, ...})
+var Mem = NewMemDriver()
+
+func init() {
+	sql.Register(MemDriverName, Mem)
+}
+
+/**
+ * MemDriver 内存中的假数据库驱动，用于在没有真实数据库的情况下测试配置和连接池
+ * 1. 和真实数据库一样需要先创建数据库和用户(CreateDatabase)，数据库不存在或密码错误时连接失败
+ * 2. 只支持三个语句：SET(参数key, value)、GET(参数key，返回value列)、KEYS(返回key列，按key排序)
+ * 3. 不支持事务
+ * 4. OpenConns返回当前打开的连接数，可以用来观察连接池的行为
+ */
+type MemDriver struct {
+	mu    sync.Mutex
+	dbs   map[string]*memDatabase
+	conns int
+}
+
+type memDatabase struct {
+	user     string
+	password string
+	data     map[string]string
+}
+
+func NewMemDriver() *MemDriver {
+	return &MemDriver{dbs: make(map[string]*memDatabase)}
+}
+
+// CreateDatabase 创建数据库和可以访问它的用户，数据库已存在时清空数据
+func (d *MemDriver) CreateDatabase(name, user, password string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dbs[name] = &memDatabase{user: user, password: password, data: make(map[string]string)}
+}
+
+// OpenConns 返回当前打开的连接数
+func (d *MemDriver) OpenConns() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns
+}
+
+// Open 实现driver.Driver，dsn的格式和Config.DSN()一致
+func (d *MemDriver) Open(dsn string) (driver.Conn, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[cfg.Database]
+	if !ok {
+		return nil, fmt.Errorf("memdb: 数据库%q不存在", cfg.Database)
+	}
+	if cfg.Username != db.user || cfg.Password != db.password {
+		return nil, fmt.Errorf("memdb: 用户%q认证失败", cfg.Username)
+	}
+	d.conns++
+	return &memConn{d: d, db: db}, nil
+}
+
+type memConn struct {
+	d      *MemDriver
+	db     *memDatabase
+	closed bool
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	cmd := strings.ToUpper(strings.TrimSpace(query))
+	switch cmd {
+	case "SET", "GET", "KEYS":
+		return &memStmt{c: c, cmd: cmd}, nil
+	}
+	return nil, fmt.Errorf("memdb: 不支持的语句%q", query)
+}
+
+func (c *memConn) Close() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.d.conns--
+	}
+	return nil
+}
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("memdb: 不支持事务")
+}
+
+type memStmt struct {
+	c   *memConn
+	cmd string
+}
+
+func (s *memStmt) Close() error { return nil }
+
+// NumInput 返回-1，由Exec和Query自己检查参数个数
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.cmd != "SET" {
+		return nil, fmt.Errorf("memdb: %s需要使用Query", s.cmd)
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("memdb: SET需要2个参数，实际为%d个", len(args))
+	}
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	s.c.db.data[toString(args[0])] = toString(args[1])
+	return driver.RowsAffected(1), nil
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.d.mu.Lock()
+	defer s.c.d.mu.Unlock()
+	switch s.cmd {
+	case "GET":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("memdb: GET需要1个参数，实际为%d个", len(args))
+		}
+		rows := &memRows{column: "value"}
+		if v, ok := s.c.db.data[toString(args[0])]; ok {
+			rows.values = []string{v}
+		}
+		return rows, nil
+	case "KEYS":
+		rows := &memRows{column: "key"}
+		for k := range s.c.db.data {
+			rows.values = append(rows.values, k)
+		}
+		sort.Strings(rows.values)
+		return rows, nil
+	}
+	return nil, fmt.Errorf("memdb: %s需要使用Exec", s.cmd)
+}
+
+// memRows 只有一列的结果
+type memRows struct {
+	column string
+	values []string
+}
+
+func (r *memRows) Columns() []string { return []string{r.column} }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func toString(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+/**
+ * Pool 数据库连接池
+ * database/sql的*sql.DB本身就是连接池：按需创建连接，用完后放回空闲队列，连接断开时自动重连
+ * Pool嵌入*sql.DB，可以直接调用QueryContext、ExecContext等方法，额外记录了连接配置
+ */
+type Pool struct {
+	*sql.DB
+	cfg Config
+}
+
+/**
+ * Open 校验配置，打开连接池并设置连接池参数，最后用Ping确认数据库可以连接
+ * sql.Open并不会真正建立连接，不Ping的话配置错误要等到第一次查询时才会发现
+ * 返回的错误中的配置已经隐藏了密码
+ * 驱动需要先通过sql.Register注册(通常是导入驱动包)，Config.DSN()的结果原样传给驱动
+ */
+func Open(ctx context.Context, cfg Config) (*Pool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	sqlDB, err := sql.Open(cfg.Driver, cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("db: 打开%s失败: %w", cfg, err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("db: 连接%s失败: %w", cfg, err)
+	}
+	return &Pool{DB: sqlDB, cfg: cfg}, nil
+}
+
+// Config 返回连接池的配置
+func (p *Pool) Config() Config {
+	return p.cfg
+}
+
+// String 返回隐藏了密码的DSN和连接池的状态
+func (p *Pool) String() string {
+	s := p.Stats()
+	return fmt.Sprintf("%s open:%d inUse:%d idle:%d", p.cfg, s.OpenConnections, s.InUse, s.Idle)
+}
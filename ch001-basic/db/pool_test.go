@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memConfig 返回连接到内存驱动中新建的数据库name的配置
+func memConfig(name string) Config {
+	Mem.CreateDatabase(name, "root", "secret")
+	return Config{Driver: MemDriverName, Host: "localhost", Username: "root", Password: "secret", Database: name}
+}
+
+func TestOpenAndQuery(t *testing.T) {
+	ctx := context.Background()
+	p, err := Open(ctx, memConfig("open"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	for k, v := range map[string]string{"b": "2", "a": "1"} {
+		if _, err := p.ExecContext(ctx, "SET", k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var v string
+	if err := p.QueryRowContext(ctx, "GET", "a").Scan(&v); err != nil || v != "1" {
+		t.Fatalf("GET a = %q, %v", v, err)
+	}
+	if err := p.QueryRowContext(ctx, "GET", "missing").Scan(&v); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GET missing: %v", err)
+	}
+	rows, err := p.QueryContext(ctx, "KEYS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for rows.Next() {
+		var k string
+		rows.Scan(&k)
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil || strings.Join(keys, ",") != "a,b" {
+		t.Fatalf("KEYS = %v, %v", keys, err)
+	}
+	if _, err := p.BeginTx(ctx, nil); err == nil {
+		t.Error("memdb should not support transactions")
+	}
+	if strings.Contains(p.String(), "secret") {
+		t.Errorf("Pool.String leaks password: %s", p)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Open(ctx, Config{Driver: MemDriverName}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("invalid config: %v", err)
+	}
+
+	cfg := memConfig("auth")
+	cfg.Password = "wrong-secret"
+	_, err := Open(ctx, cfg)
+	if err == nil || !strings.Contains(err.Error(), "认证失败") {
+		t.Fatalf("wrong password: %v", err)
+	}
+	if strings.Contains(err.Error(), "wrong-secret") {
+		t.Errorf("error leaks password: %v", err)
+	}
+
+	cfg = memConfig("x")
+	cfg.Database = "nope"
+	if _, err := Open(ctx, cfg); err == nil || !strings.Contains(err.Error(), "不存在") {
+		t.Errorf("missing database: %v", err)
+	}
+	cfg.Driver = "unknown"
+	if _, err := Open(ctx, cfg); err == nil {
+		t.Error("unknown driver should fail")
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	ctx := context.Background()
+	cfg := memConfig("limits")
+	cfg.MaxOpenConns, cfg.MaxIdleConns = 2, 1
+	before := Mem.OpenConns()
+	p, err := Open(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同时占用两个连接，第三个请求在MaxOpenConns=2时只能等待
+	c1, _ := p.Conn(ctx)
+	c2, _ := p.Conn(ctx)
+	if got := p.Stats().InUse; got != 2 {
+		t.Fatalf("InUse = %d", got)
+	}
+	waitCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.Conn(waitCtx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("third conn: %v", err)
+	}
+	// 归还后最多保留1个空闲连接，多余的被关闭
+	c1.Close()
+	c2.Close()
+	if s := p.Stats(); s.Idle != 1 || s.OpenConnections != 1 || Mem.OpenConns()-before != 1 {
+		t.Fatalf("after release: %+v, driver conns %d", s, Mem.OpenConns()-before)
+	}
+	p.Close()
+	if got := Mem.OpenConns() - before; got != 0 {
+		t.Errorf("conns after Close = %d", got)
+	}
+}
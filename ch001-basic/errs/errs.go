@@ -0,0 +1,183 @@
+/**
+ * errs包是error_panic.go中commonError的完整版本，可以在服务中直接使用
+ * 1. 错误码：每个错误都有一个Code，Code本身也实现了error，可以直接用errors.Is(err, errs.NotFound)判断
+ * 2. 包装：Wrap把底层错误包装为带错误码的错误，实现了Unwrap，errors.Is/As可以沿着错误链查找
+ * 3. 附加字段：With为错误附加上下文(例如参数值、用户ID)，方便打日志
+ * 4. 调用栈：WithStack记录创建错误时的调用栈，只在需要排查问题的地方使用，避免不必要的开销
+ * 5. 注册表：每个错误码对应一个HTTP状态码和面向用户的提示信息，内部错误信息不会直接暴露给用户
+ */
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Code 错误码，实现了error，可以作为errors.Is的target
+type Code int
+
+const (
+	OK              Code = 0
+	Unknown         Code = 1 // 未知错误，没有错误码的error都视为Unknown
+	InvalidArgument Code = 2
+	NotFound        Code = 3
+	Unauthorized    Code = 4
+	Forbidden       Code = 5
+	Conflict        Code = 6
+	TooManyRequests Code = 7
+	Internal        Code = 8
+)
+
+func (c Code) Error() string {
+	if def, ok := Lookup(c); ok && def.Name != "" {
+		return def.Name
+	}
+	return fmt.Sprintf("code(%d)", int(c))
+}
+
+// Field 附加在错误上的字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Error 带错误码的错误
+type Error struct {
+	Code   Code
+	Msg    string // 内部错误信息，用于日志，不直接展示给用户
+	Err    error  // 被包装的底层错误，可以为nil
+	fields []Field
+	stack  []uintptr
+}
+
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Wrap 用错误码和信息包装err，err为nil时返回nil
+// 返回值的类型是error而不是*Error，避免出现"值为nil的*Error不等于nil的error"的问题
+func Wrap(err error, code Code, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Msg: msg, Err: err}
+}
+
+// Wrapf 同Wrap，msg按format格式化
+func Wrapf(err error, code Code, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Error 格式为"msg: 底层错误"，msg为空时使用错误码的名称
+func (e *Error) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = e.Code.Error()
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误码相同即视为匹配，target可以是Code或*Error
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case Code:
+		return e.Code == t
+	case *Error:
+		return e.Code == t.Code
+	}
+	return false
+}
+
+// With 返回附加了字段的副本，不修改e，所以可以安全地用在包级别的错误变量上
+func (e *Error) With(key string, value interface{}) *Error {
+	cp := *e
+	cp.fields = append(append([]Field(nil), e.fields...), Field{Key: key, Value: value})
+	return &cp
+}
+
+// Fields 返回附加的字段，按添加的顺序
+func (e *Error) Fields() []Field {
+	return append([]Field(nil), e.fields...)
+}
+
+// WithStack 返回记录了当前调用栈的副本
+func (e *Error) WithStack() *Error {
+	cp := *e
+	pcs := make([]uintptr, 32)
+	// 跳过runtime.Callers和WithStack本身
+	n := runtime.Callers(2, pcs)
+	cp.stack = pcs[:n]
+	return &cp
+}
+
+// Stack 返回WithStack记录的调用栈，每行一个"函数名\n\t文件:行号"，没有记录时返回空字符串
+func (e *Error) Stack() string {
+	if len(e.stack) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// CodeOf 返回错误链中第一个*Error的错误码，err为nil时返回OK，没有错误码时返回Unknown
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var c Code
+	if errors.As(err, &c) {
+		return c
+	}
+	return Unknown
+}
+
+// FieldsOf 收集错误链中所有*Error附加的字段，外层的在前
+func FieldsOf(err error) []Field {
+	var fields []Field
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			fields = append(fields, e.fields...)
+		}
+		err = errors.Unwrap(err)
+	}
+	return fields
+}
+
+// StackOf 返回错误链中第一个记录了调用栈的*Error的调用栈
+func StackOf(err error) string {
+	for err != nil {
+		if e, ok := err.(*Error); ok && len(e.stack) > 0 {
+			return e.Stack()
+		}
+		err = errors.Unwrap(err)
+	}
+	return ""
+}
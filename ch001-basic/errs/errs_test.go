@@ -0,0 +1,110 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWrapAndIs(t *testing.T) {
+	base := io.ErrUnexpectedEOF
+	err := fmt.Errorf("handler: %w", Wrap(base, NotFound, "load user").(*Error).With("id", 7))
+
+	if got := err.Error(); got != "handler: load user: unexpected EOF" {
+		t.Errorf("Error() = %q", got)
+	}
+	if !errors.Is(err, NotFound) || !errors.Is(err, New(NotFound, "other msg")) {
+		t.Error("errors.Is should match by code")
+	}
+	if errors.Is(err, Internal) {
+		t.Error("errors.Is matched a different code")
+	}
+	if !errors.Is(err, base) {
+		t.Error("errors.Is should reach the wrapped error")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Code != NotFound || e.Msg != "load user" {
+		t.Fatalf("errors.As = %+v", e)
+	}
+	if f := e.Fields(); len(f) != 1 || f[0].Key != "id" || f[0].Value != 7 {
+		t.Errorf("fields = %v", f)
+	}
+	if CodeOf(err) != NotFound || CodeOf(nil) != OK || CodeOf(base) != Unknown || CodeOf(Conflict) != Conflict {
+		t.Error("CodeOf")
+	}
+	if Wrap(nil, Internal, "x") != nil || Wrapf(nil, Internal, "%d", 1) != nil || Public(nil, "x") != nil {
+		t.Error("wrapping nil should return nil")
+	}
+}
+
+func TestWithDoesNotMutate(t *testing.T) {
+	sentinel := New(InvalidArgument, "bad")
+	a := sentinel.With("k", 1)
+	b := a.With("k2", 2)
+	if len(sentinel.Fields()) != 0 || len(a.Fields()) != 1 || len(b.Fields()) != 2 {
+		t.Fatalf("fields: %v %v %v", sentinel.Fields(), a.Fields(), b.Fields())
+	}
+	outer := Wrap(b, Internal, "outer").(*Error).With("o", true)
+	if f := FieldsOf(fmt.Errorf("x: %w", outer)); len(f) != 3 || f[0].Key != "o" || f[2].Key != "k2" {
+		t.Errorf("FieldsOf = %v", f)
+	}
+}
+
+func TestStack(t *testing.T) {
+	err := New(Internal, "boom")
+	if err.Stack() != "" || StackOf(err) != "" {
+		t.Fatal("stack should be empty without WithStack")
+	}
+	withStack := err.WithStack()
+	if !strings.Contains(withStack.Stack(), "errs.TestStack") {
+		t.Errorf("stack missing caller:\n%s", withStack.Stack())
+	}
+	if StackOf(fmt.Errorf("wrap: %w", withStack)) == "" {
+		t.Error("StackOf should find stack through the chain")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		msg    string
+	}{
+		{nil, http.StatusOK, "成功"},
+		{errors.New("plain"), http.StatusInternalServerError, "未知错误"},
+		{New(NotFound, "secret detail"), http.StatusNotFound, "资源不存在"},
+		{fmt.Errorf("x: %w", Public(New(InvalidArgument, "y"), "名字不能为空")), http.StatusBadRequest, "名字不能为空"},
+		{New(Code(9999), "unregistered"), http.StatusInternalServerError, "未知错误"},
+	}
+	for _, c := range cases {
+		if got := HTTPStatus(c.err); got != c.status {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", c.err, got, c.status)
+		}
+		if got := UserMessage(c.err); got != c.msg {
+			t.Errorf("UserMessage(%v) = %q, want %q", c.err, got, c.msg)
+		}
+	}
+	if NotFound.Error() != "not_found" || Code(9999).Error() != "code(9999)" {
+		t.Error("Code.Error")
+	}
+	if New(NotFound, "").Error() != "not_found" {
+		t.Error("empty message should fall back to the code name")
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	const code Code = 1000
+	Register(Definition{Code: code, Name: "custom", HTTPStatus: http.StatusTeapot, Message: "custom"})
+	if HTTPStatus(New(code, "")) != http.StatusTeapot {
+		t.Error("custom code status")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate Register should panic")
+		}
+	}()
+	Register(Definition{Code: code, Name: "again"})
+}
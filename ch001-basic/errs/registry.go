@@ -0,0 +1,100 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Definition 错误码的定义：名称、对应的HTTP状态码和面向用户的提示信息
+type Definition struct {
+	Code       Code
+	Name       string
+	HTTPStatus int
+	Message    string // 返回给用户的提示信息，不包含内部细节
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[Code]Definition)
+)
+
+func init() {
+	for _, def := range []Definition{
+		{OK, "ok", http.StatusOK, "成功"},
+		{Unknown, "unknown", http.StatusInternalServerError, "未知错误"},
+		{InvalidArgument, "invalid_argument", http.StatusBadRequest, "参数错误"},
+		{NotFound, "not_found", http.StatusNotFound, "资源不存在"},
+		{Unauthorized, "unauthorized", http.StatusUnauthorized, "请先登录"},
+		{Forbidden, "forbidden", http.StatusForbidden, "没有权限"},
+		{Conflict, "conflict", http.StatusConflict, "资源冲突"},
+		{TooManyRequests, "too_many_requests", http.StatusTooManyRequests, "请求过于频繁，请稍后再试"},
+		{Internal, "internal", http.StatusInternalServerError, "服务器内部错误"},
+	} {
+		Register(def)
+	}
+}
+
+// Register 注册错误码，错误码重复时panic，一般在init中调用
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if old, ok := registry[def.Code]; ok {
+		panic(fmt.Sprintf("errs: code %d already registered as %q", int(def.Code), old.Name))
+	}
+	if def.HTTPStatus == 0 {
+		def.HTTPStatus = http.StatusInternalServerError
+	}
+	registry[def.Code] = def
+}
+
+// Lookup 查找错误码的定义
+func Lookup(code Code) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	def, ok := registry[code]
+	return def, ok
+}
+
+// HTTPStatus 返回err的错误码对应的HTTP状态码，err为nil时返回200，未注册的错误码返回500
+func HTTPStatus(err error) int {
+	if def, ok := Lookup(CodeOf(err)); ok {
+		return def.HTTPStatus
+	}
+	return http.StatusInternalServerError
+}
+
+/**
+ * UserMessage 返回可以展示给用户的提示信息
+ * 优先使用错误链中通过Public设置的信息，其次是错误码注册的信息，都没有时返回Unknown的信息
+ */
+func UserMessage(err error) string {
+	var p *publicError
+	if errors.As(err, &p) {
+		return p.msg
+	}
+	if def, ok := Lookup(CodeOf(err)); ok {
+		return def.Message
+	}
+	def, _ := Lookup(Unknown)
+	return def.Message
+}
+
+// publicError 携带面向用户的信息
+type publicError struct {
+	msg string
+	err error
+}
+
+func (p *publicError) Error() string { return p.err.Error() }
+func (p *publicError) Unwrap() error { return p.err }
+
+// Public 为err设置面向用户的提示信息，用于比注册表中的通用信息更具体的场景，例如"用户名不能为空"
+// 不改变err的Error()、错误码和errors.Is/As的结果，err为nil时返回nil
+func Public(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &publicError{msg: msg, err: err}
+}
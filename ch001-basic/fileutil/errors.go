@@ -0,0 +1,87 @@
+/**
+ * fileutil包是defer.go中ReadFile的完整版本：ReadFile把整个文件读入内存，也只能区分文件不存在这一种错误
+ * 1. 读取：ReadLines/ReadChunks按行或按块流式读取，ReadAll一次读入，都可以限制文件大小，并在ctx取消时停止
+ * 2. 写入：WriteAtomic先写临时文件，fsync后rename为目标文件，再fsync目录，读者要么看到旧文件，要么看到完整的新文件
+ * 3. 文件锁：Lock/TryLock基于flock，多个进程(例如同一个工具的多个实例)可以用它互斥地修改同一个文件
+ * 4. 错误：返回的错误都是*Error，可以用errors.Is区分ErrNotExist、ErrPermission、ErrTooLarge
+ */
+package fileutil
+
+import (
+	"errors"
+	"io/fs"
+)
+
+var (
+	ErrNotExist   = errors.New("文件不存在")
+	ErrPermission = errors.New("没有权限")
+	ErrTooLarge   = errors.New("超过大小限制")
+	ErrLocked     = errors.New("文件已被其他进程锁定")
+)
+
+/**
+ * Error 文件操作的错误
+ * Kind是上面的错误之一，没有对应的分类时为nil；Err是底层的错误
+ * errors.Is(err, ErrNotExist)和errors.Is(err, fs.ErrNotExist)都可以使用
+ */
+type Error struct {
+	Op   string
+	Path string
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	s := "fileutil: " + e.Op
+	if e.Path != "" {
+		s += " " + e.Path
+	}
+	if e.Kind != nil {
+		s += ": " + e.Kind.Error()
+	}
+	// *fs.PathError的信息中已经包含了操作和路径，只取最内层的原因
+	err := e.Err
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	if err != nil {
+		s += ": " + err.Error()
+	}
+	return s
+}
+
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// wrap 把err包装为*Error并根据err分类，err已经是*Error时只补上缺少的路径
+func wrap(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		if e.Path == "" {
+			cp := *e
+			cp.Path = path
+			return &cp
+		}
+		return err
+	}
+	e = &Error{Op: op, Path: path, Err: err}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		e.Kind = ErrNotExist
+	case errors.Is(err, fs.ErrPermission):
+		e.Kind = ErrPermission
+	}
+	return e
+}
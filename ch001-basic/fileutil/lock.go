@@ -0,0 +1,64 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// lockRetryInterval Lock等待其他进程释放锁时重试的间隔
+const lockRetryInterval = 50 * time.Millisecond
+
+/**
+ * FileLock 文件锁，用于多个进程之间互斥，例如同一个工具同时运行了多个实例
+ * 基于flock，是建议锁：只对同样使用锁的进程有效，不会阻止其他进程直接读写文件
+ * 锁在Unlock或进程退出(包括崩溃)时释放，不会因为进程异常退出而残留
+ * 非unix系统上不支持，返回的错误满足errors.Is(err, errors.ErrUnsupported)
+ */
+type FileLock struct {
+	f *os.File
+}
+
+// TryLock 尝试锁定文件name(不存在时创建)，已被锁定时立即返回ErrLocked
+func TryLock(name string) (*FileLock, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, wrap("lock", name, err)
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		e := &Error{Op: "lock", Path: name, Err: err}
+		if errors.Is(err, errWouldBlock) {
+			e.Kind, e.Err = ErrLocked, nil
+		}
+		return nil, e
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Lock 锁定文件name，已被锁定时等待，直到锁被释放或者ctx结束
+func Lock(ctx context.Context, name string) (*FileLock, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+	for {
+		l, err := TryLock(name)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, &Error{Op: "lock", Path: name, Kind: ErrLocked, Err: ctx.Err()}
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock 释放锁并关闭文件，锁文件本身不会被删除
+func (l *FileLock) Unlock() error {
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return wrap("unlock", l.f.Name(), err)
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+)
+
+// errWouldBlock 非unix系统上不会出现
+var errWouldBlock = errors.New("fileutil: would block")
+
+func tryLock(f *os.File) error {
+	return errors.ErrUnsupported
+}
+
+func unlock(f *os.File) error {
+	return errors.ErrUnsupported
+}
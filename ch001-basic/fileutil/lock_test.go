@@ -0,0 +1,55 @@
+//go:build unix
+
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.lock")
+	l1, err := TryLock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flock对每次打开的文件单独加锁，同一个进程中再打开一次也会冲突
+	if _, err := TryLock(name); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second TryLock: %v", err)
+	}
+	if err := l1.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	l2, err := TryLock(name)
+	if err != nil {
+		t.Fatalf("TryLock after Unlock: %v", err)
+	}
+	l2.Unlock()
+}
+
+func TestLockWaits(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.lock")
+	l1, err := TryLock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockRetryInterval)
+	defer cancel()
+	if _, err := Lock(ctx, name); !errors.Is(err, ErrLocked) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock with timeout: %v", err)
+	}
+
+	go func() {
+		time.Sleep(lockRetryInterval)
+		l1.Unlock()
+	}()
+	l2, err := Lock(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Lock after release: %v", err)
+	}
+	l2.Unlock()
+}
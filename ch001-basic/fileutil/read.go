@@ -0,0 +1,184 @@
+package fileutil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Options 读取选项，零值表示不限制文件大小并使用默认的行和块大小
+type Options struct {
+	MaxSize   int64 // 最多读取的字节数，超过时返回ErrTooLarge，0表示不限制
+	MaxLine   int   // ReadLines单行的最大字节数，默认64KB
+	ChunkSize int   // ReadChunks每块的大小，默认32KB
+}
+
+func (opt *Options) setDefaults() {
+	if opt.MaxLine <= 0 {
+		opt.MaxLine = bufio.MaxScanTokenSize
+	}
+	if opt.ChunkSize <= 0 {
+		opt.ChunkSize = 32 * 1024
+	}
+}
+
+// Open 打开文件用于读取，和os.Open相同，只是返回的错误是*Error
+func Open(name string) (*os.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, wrap("open", name, err)
+	}
+	return f, nil
+}
+
+// ReadAll 读取整个文件，设置了MaxSize时先检查文件大小，超过限制的文件不会被读取
+func ReadAll(ctx context.Context, name string, opt Options) ([]byte, error) {
+	f, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if opt.MaxSize > 0 {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && fi.Size() > opt.MaxSize {
+			return nil, tooLarge(name, opt.MaxSize)
+		}
+	}
+	b, err := ReadAllFrom(ctx, f, opt)
+	return b, wrap("read", name, err)
+}
+
+// ReadAllFrom 从r读取全部数据，超过MaxSize时返回ErrTooLarge
+func ReadAllFrom(ctx context.Context, r io.Reader, opt Options) ([]byte, error) {
+	b, err := io.ReadAll(newGuardReader(ctx, r, opt.MaxSize))
+	if err != nil {
+		return nil, wrap("read", "", err)
+	}
+	return b, nil
+}
+
+/**
+ * ReadLines 按行读取文件，每行(不包含换行符)调用一次fn，不会把整个文件读入内存
+ * fn返回错误时停止读取并原样返回该错误，单行超过MaxLine或文件超过MaxSize时返回ErrTooLarge
+ */
+func ReadLines(ctx context.Context, name string, opt Options, fn func(line string) error) error {
+	f, err := Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return wrapRead(name, scanLines(ctx, f, opt, fn))
+}
+
+// ScanLines 同ReadLines，从r读取
+func ScanLines(ctx context.Context, r io.Reader, opt Options, fn func(line string) error) error {
+	return wrapRead("", scanLines(ctx, r, opt, fn))
+}
+
+func scanLines(ctx context.Context, r io.Reader, opt Options, fn func(line string) error) error {
+	opt.setDefaults()
+	sc := bufio.NewScanner(newGuardReader(ctx, r, opt.MaxSize))
+	sc.Buffer(make([]byte, 0, min(opt.MaxLine, 4096)), opt.MaxLine)
+	for sc.Scan() {
+		if err := fn(sc.Text()); err != nil {
+			return &callbackError{err}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return &Error{Op: "read", Kind: ErrTooLarge, Err: fmt.Errorf("单行超过%d字节", opt.MaxLine)}
+		}
+		return wrap("read", "", err)
+	}
+	return nil
+}
+
+/**
+ * ReadChunks 按块读取文件，每块最多ChunkSize字节，最后一块可能更小
+ * chunk在fn返回后会被复用，fn需要保留数据时应复制一份
+ */
+func ReadChunks(ctx context.Context, name string, opt Options, fn func(chunk []byte) error) error {
+	f, err := Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return wrapRead(name, scanChunks(ctx, f, opt, fn))
+}
+
+// ScanChunks 同ReadChunks，从r读取
+func ScanChunks(ctx context.Context, r io.Reader, opt Options, fn func(chunk []byte) error) error {
+	return wrapRead("", scanChunks(ctx, r, opt, fn))
+}
+
+func scanChunks(ctx context.Context, r io.Reader, opt Options, fn func(chunk []byte) error) error {
+	opt.setDefaults()
+	gr := newGuardReader(ctx, r, opt.MaxSize)
+	buf := make([]byte, opt.ChunkSize)
+	for {
+		n, err := io.ReadFull(gr, buf)
+		if n > 0 {
+			if ferr := fn(buf[:n]); ferr != nil {
+				return &callbackError{ferr}
+			}
+		}
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return nil
+		case err != nil:
+			return wrap("read", "", err)
+		}
+	}
+}
+
+// callbackError 标记fn返回的错误，wrapRead据此原样返回，不包装为*Error
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string { return e.err.Error() }
+
+// wrapRead 给读取时的错误加上文件路径，fn返回的错误原样返回
+func wrapRead(name string, err error) error {
+	var ce *callbackError
+	if errors.As(err, &ce) {
+		return ce.err
+	}
+	return wrap("read", name, err)
+}
+
+func tooLarge(name string, max int64) error {
+	return &Error{Op: "read", Path: name, Kind: ErrTooLarge, Err: fmt.Errorf("超过%d字节", max)}
+}
+
+// guardReader 每次Read前检查ctx，读取的总字节数超过max(大于0时)时返回ErrTooLarge
+type guardReader struct {
+	ctx  context.Context
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func newGuardReader(ctx context.Context, r io.Reader, max int64) *guardReader {
+	return &guardReader{ctx: ctx, r: r, max: max}
+}
+
+func (g *guardReader) Read(p []byte) (int, error) {
+	if err := g.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if g.max > 0 {
+		// 多读一个字节，用来判断是否超过了限制
+		if left := g.max - g.read + 1; int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err := g.r.Read(p)
+	g.read += int64(n)
+	if g.max > 0 && g.read > g.max {
+		return n - int(g.read-g.max), tooLarge("", g.max)
+	}
+	return n, err
+}
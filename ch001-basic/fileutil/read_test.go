@@ -0,0 +1,129 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemp(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestReadAll(t *testing.T) {
+	ctx := context.Background()
+	name := writeTemp(t, "hello")
+	if b, err := ReadAll(ctx, name, Options{MaxSize: 5}); err != nil || string(b) != "hello" {
+		t.Fatalf("ReadAll = %q, %v", b, err)
+	}
+	_, err := ReadAll(ctx, name, Options{MaxSize: 4})
+	if !errors.Is(err, ErrTooLarge) || !strings.Contains(err.Error(), name) {
+		t.Fatalf("too large: %v", err)
+	}
+	// 不是普通文件时无法事先知道大小，读取时检查
+	if _, err := ReadAllFrom(ctx, strings.NewReader("hello"), Options{MaxSize: 4}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ReadAllFrom too large: %v", err)
+	}
+
+	_, err = ReadAll(ctx, filepath.Join(t.TempDir(), "missing"), Options{})
+	if !errors.Is(err, ErrNotExist) || !errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrPermission) {
+		t.Fatalf("missing: %v", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "open" {
+		t.Fatalf("errors.As: %#v", e)
+	}
+}
+
+func TestReadPermission(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root不受文件权限限制")
+	}
+	name := writeTemp(t, "secret")
+	os.Chmod(name, 0)
+	if _, err := Open(name); !errors.Is(err, ErrPermission) {
+		t.Fatalf("permission: %v", err)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	ctx := context.Background()
+	name := writeTemp(t, "a\nbb\r\nccc")
+	var lines []string
+	err := ReadLines(ctx, name, Options{}, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil || strings.Join(lines, "|") != "a|bb|ccc" {
+		t.Fatalf("lines = %q, %v", lines, err)
+	}
+
+	// fn返回的错误原样返回
+	errStop := errors.New("stop")
+	n := 0
+	err = ReadLines(ctx, name, Options{}, func(string) error {
+		n++
+		return errStop
+	})
+	if err != errStop || n != 1 {
+		t.Fatalf("stop: %v after %d lines", err, n)
+	}
+
+	noop := func(string) error { return nil }
+	if err := ReadLines(ctx, name, Options{MaxLine: 2}, noop); !errors.Is(err, ErrTooLarge) || !strings.Contains(err.Error(), "单行") {
+		t.Errorf("long line: %v", err)
+	}
+	if err := ReadLines(ctx, name, Options{MaxSize: 5}, noop); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("max size: %v", err)
+	}
+}
+
+func TestReadLinesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := strings.NewReader(strings.Repeat("line\n", 100000))
+	n := 0
+	err := ScanLines(ctx, r, Options{}, func(string) error {
+		if n++; n == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	// 已经读入缓冲区的行仍然会处理完，但不会继续读取整个输入
+	if n >= 100000 {
+		t.Errorf("read all %d lines after cancel", n)
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+	ctx := context.Background()
+	name := writeTemp(t, "abcdefg")
+	var chunks []string
+	err := ReadChunks(ctx, name, Options{ChunkSize: 3}, func(c []byte) error {
+		chunks = append(chunks, string(c))
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "abc|def|g" {
+		t.Fatalf("chunks = %q, %v", chunks, err)
+	}
+	chunks = nil
+	err = ReadChunks(ctx, name, Options{ChunkSize: 3, MaxSize: 5}, func(c []byte) error {
+		chunks = append(chunks, string(c))
+		return nil
+	})
+	// 超过限制之前的数据已经交给fn
+	if !errors.Is(err, ErrTooLarge) || strings.Join(chunks, "") != "abcde" {
+		t.Fatalf("max size: %q, %v", chunks, err)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package fileutil
+
+// syncDir 非unix系统(例如windows)不支持打开目录并fsync，rename的持久性由文件系统保证
+func syncDir(dir string) error {
+	return nil
+}
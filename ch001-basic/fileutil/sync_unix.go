@@ -0,0 +1,15 @@
+//go:build unix
+
+package fileutil
+
+import "os"
+
+// syncDir fsync目录，使目录中的rename、创建等操作落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
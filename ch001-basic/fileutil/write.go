@@ -0,0 +1,67 @@
+package fileutil
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomic 原子地把data写入文件name，参见WriteAtomicFunc
+func WriteAtomic(name string, data []byte, perm fs.FileMode) error {
+	return WriteAtomicFunc(name, perm, func(w io.Writer) error {
+		_, err := io.Copy(w, bytes.NewReader(data))
+		return err
+	})
+}
+
+/**
+ * WriteAtomicFunc 原子地写入文件name，文件内容由fn写入
+ * 直接os.WriteFile时，程序崩溃或断电可能留下只写了一半的文件，步骤如下：
+ * 1. 在同一个目录下创建临时文件(rename只有在同一个文件系统内才是原子的)，由fn写入内容
+ * 2. fsync临时文件，确保内容已经落盘，再rename为目标文件，读者要么看到旧文件，要么看到完整的新文件
+ * 3. fsync目录，确保rename本身也已经落盘
+ * 任何一步失败(包括fn panic)都会删除临时文件，目标文件保持不变
+ * 注意文件权限按perm原样设置，不受umask影响，这一点和os.WriteFile不同
+ */
+func WriteAtomicFunc(name string, perm fs.FileMode, fn func(w io.Writer) error) (err error) {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return wrap("write", name, err)
+	}
+	renamed := false
+	defer func() {
+		// 只要没有rename成功就删除临时文件，fn panic时err为nil，不能以err判断
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = fn(tmp); err != nil {
+		return wrap("write", name, err)
+	}
+	// CreateTemp创建的文件权限为0600，改为调用者指定的权限，Chmod不受umask影响
+	if err = tmp.Chmod(perm); err != nil {
+		return wrap("chmod", name, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return wrap("sync", name, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return wrap("close", name, err)
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return wrap("rename", name, err)
+	}
+	renamed = true
+	if err = syncDir(dir); err != nil {
+		// 文件已经替换成功，只是不能保证rename已经落盘
+		return wrap("sync", dir, err)
+	}
+	return nil
+}
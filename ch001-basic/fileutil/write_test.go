@@ -0,0 +1,99 @@
+package fileutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	if err := WriteAtomic(name, []byte("v1"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAtomic(name, []byte("v2"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil || string(b) != "v2" {
+		t.Fatalf("content = %q, %v", b, err)
+	}
+	if fi, _ := os.Stat(name); fi.Mode().Perm() != 0o640 {
+		t.Errorf("perm = %v", fi.Mode().Perm())
+	}
+	assertOnlyFile(t, dir, "config.json")
+}
+
+func TestWriteAtomicFailureKeepsOldFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	os.WriteFile(name, []byte("old"), 0o644)
+
+	errWrite := errors.New("disk full")
+	err := WriteAtomicFunc(name, 0o644, func(w io.Writer) error {
+		w.Write([]byte("half"))
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("err = %v", err)
+	}
+	if b, _ := os.ReadFile(name); string(b) != "old" {
+		t.Errorf("old file changed: %q", b)
+	}
+	// 临时文件已经删除
+	assertOnlyFile(t, dir, "data")
+
+	if err := WriteAtomic(filepath.Join(dir, "missing", "x"), nil, 0o644); !errors.Is(err, ErrNotExist) {
+		t.Errorf("missing dir: %v", err)
+	}
+}
+
+func TestWriteAtomicPanicRemovesTemp(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	os.WriteFile(name, []byte("old"), 0o644)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("fn的panic没有向上传播")
+			}
+		}()
+		WriteAtomicFunc(name, 0o644, func(w io.Writer) error {
+			w.Write([]byte("half"))
+			panic("boom")
+		})
+	}()
+	if b, _ := os.ReadFile(name); string(b) != "old" {
+		t.Errorf("old file changed: %q", b)
+	}
+	assertOnlyFile(t, dir, "data")
+}
+
+func TestWriteAtomicIgnoresUmask(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "shared")
+	// 0o666通常会被umask(如022)去掉组和其他人的写权限，WriteAtomic按perm原样设置
+	if err := WriteAtomic(name, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(name); fi.Mode().Perm() != 0o666 {
+		t.Errorf("perm = %v", fi.Mode().Perm())
+	}
+}
+
+func assertOnlyFile(t *testing.T, dir, want string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != want {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("files in dir = %v, want only %s", names, want)
+	}
+}
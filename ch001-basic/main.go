@@ -1,19 +1,94 @@
 package main
 
-import "go-practice/ch001-basic/basic"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"go-practice/ch001-basic/basic"
+	"go-practice/internal/randseed"
+)
+
+const usage = `用法:
+  go-practice [-seed N] list              列出所有演示
+  go-practice [-seed N] run <name>...     按名称运行一个或多个演示
+  go-practice [-seed N] run --all         按章节和名称顺序运行所有演示
+
+依赖随机数的演示(如if、switch)使用-seed指定的种子，也可以通过环境变量GO_PRACTICE_SEED指定，
+都未指定时使用当前时间作为种子，并把种子打印到标准错误输出，方便复现
+`
 
 func main() {
-	//basic.CollectionDemo()
-	//basic.IfDemo()
-	//basic.SwitchDemo()
-	//basic.ForDemo()
-	//basic.MethodDemo()
-	//basic.StructDemo()
-	//basic.InterfaceDemo()
-	//basic.DeferDemo()
-	//basic.NewMakeDemo()
-	//basic.ErrorDemo()
-	//basic.PanicDemo()
-	//basic.TypeAssertionDemo()
-	basic.ReflectDemo()
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("go-practice", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	seedFlag := fs.String("seed", os.Getenv(randseed.Env), "随机数种子")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("缺少子命令")
+	}
+	switch cmd, rest := args[0], args[1:]; cmd {
+	case "list":
+		return list(out)
+	case "run":
+		seed, generated, err := randseed.Parse(*seedFlag)
+		if err != nil {
+			return err
+		}
+		if generated {
+			fmt.Fprintf(os.Stderr, "seed: %d\n", seed)
+		}
+		return runDemos(rest, out, seed)
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	default:
+		return fmt.Errorf("未知子命令: %s", cmd)
+	}
+}
+
+func list(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCHAPTER\tDESCRIPTION")
+	for _, d := range basic.Demos() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Name, d.Chapter, d.Description)
+	}
+	return tw.Flush()
+}
+
+func runDemos(names []string, out io.Writer, seed int64) error {
+	if len(names) == 1 && names[0] == "--all" {
+		for _, d := range basic.Demos() {
+			d.Exec(out, seed)
+		}
+		return nil
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("run需要演示名称或--all")
+	}
+	// 先校验所有名称，避免运行到一半才发现名称写错
+	selected := make([]basic.Demo, 0, len(names))
+	for _, name := range names {
+		d, ok := basic.Lookup(name)
+		if !ok {
+			return fmt.Errorf("未知演示: %s (使用list查看所有演示)", name)
+		}
+		selected = append(selected, d)
+	}
+	for _, d := range selected {
+		d.Exec(out, seed)
+	}
+	return nil
 }
@@ -0,0 +1,133 @@
+/**
+ * validate包用于参数校验时收集所有字段的错误，而不是遇到第一个错误就返回
+ * errors.New("a或者b不能为负数")这样的错误只有一句话，调用者不知道到底是哪个参数有问题
+ * 1. Validator逐个检查字段，Err()把所有问题合并为一个Errors返回，没有问题时返回nil
+ * 2. Errors实现了Unwrap() []error，和errors.Join的结果一样可以用errors.Is/errors.As查找其中的错误
+ * 3. Errors可以输出为文本(Error()，每行一个字段)或JSON(MarshalJSON)，方便直接返回给接口的调用者
+ */
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// FieldError 单个字段的错误，Err为导致错误的原因，可以用errors.Is判断
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	}{e.Field, e.Err.Error()})
+}
+
+// Errors 多个字段的错误，按添加的顺序
+type Errors []*FieldError
+
+// Error 每个字段一行，和errors.Join的格式一致
+func (es Errors) Error() string {
+	lines := make([]string, len(es))
+	for i, e := range es {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap 使errors.Is/errors.As可以查找每个字段的错误
+func (es Errors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+// MarshalJSON 输出为{"errors":[{"field":"a","message":"不能为负数"}]}
+func (es Errors) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Errors []*FieldError `json:"errors"`
+	}{[]*FieldError(es)})
+}
+
+// Fields 返回有错误的字段名，一个字段有多个错误时会出现多次
+func (es Errors) Fields() []string {
+	fields := make([]string, len(es))
+	for i, e := range es {
+		fields[i] = e.Field
+	}
+	return fields
+}
+
+// Has 报告字段field是否有错误
+func (es Errors) Has(field string) bool {
+	for _, e := range es {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator 收集字段错误，零值可以直接使用
+type Validator struct {
+	errs Errors
+}
+
+// Check ok为false时为field添加一个错误，错误信息为msg
+func (v *Validator) Check(ok bool, field, msg string) {
+	if !ok {
+		v.errs = append(v.errs, &FieldError{Field: field, Err: errors.New(msg)})
+	}
+}
+
+/**
+ * Add 为field添加错误err，err为nil时忽略
+ * err是Errors时，其中的每个错误都以"field.子字段"的名称添加，用于校验嵌套的结构
+ * err是errors.Join的结果时，其中的每个错误都单独添加到field下
+ */
+func (v *Validator) Add(field string, err error) {
+	if err == nil {
+		return
+	}
+	var nested Errors
+	if errors.As(err, &nested) && len(nested) > 0 {
+		for _, e := range nested {
+			v.errs = append(v.errs, &FieldError{Field: field + "." + e.Field, Err: e.Err})
+		}
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			v.Add(field, e)
+		}
+		return
+	}
+	v.errs = append(v.errs, &FieldError{Field: field, Err: err})
+}
+
+// Valid 报告目前是否没有错误
+func (v *Validator) Valid() bool {
+	return len(v.errs) == 0
+}
+
+// Err 没有错误时返回nil，否则返回Errors
+// 返回值的类型是error，没有错误时是真正的nil，而不是值为nil的Errors
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return append(Errors(nil), v.errs...)
+}
@@ -0,0 +1,69 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	if !v.Valid() || v.Err() != nil {
+		t.Fatal("zero Validator should be valid")
+	}
+	v.Check(false, "a", "不能为负数")
+	v.Check(true, "ok", "never")
+	v.Check(false, "b", "不能为负数")
+	v.Add("c", nil)
+	v.Add("d", io.EOF)
+
+	err := v.Err()
+	if got, want := err.Error(), "a: 不能为负数\nb: 不能为负数\nd: EOF"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	var es Errors
+	if !errors.As(err, &es) || strings.Join(es.Fields(), ",") != "a,b,d" || !es.Has("b") || es.Has("c") {
+		t.Fatalf("Errors = %v", es)
+	}
+	if !errors.Is(fmt.Errorf("wrap: %w", err), io.EOF) {
+		t.Error("errors.Is should find field errors")
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "a" {
+		t.Errorf("errors.As(*FieldError) = %v", fe)
+	}
+	// Err返回副本，继续添加不影响已经返回的错误
+	v.Check(false, "e", "x")
+	if len(es) != 3 {
+		t.Errorf("returned Errors modified: %v", es)
+	}
+}
+
+func TestAddNestedAndJoined(t *testing.T) {
+	var inner Validator
+	inner.Check(false, "host", "不能为空")
+	var v Validator
+	v.Add("db", inner.Err())
+	v.Add("port", errors.Join(errors.New("不是数字"), nil, errors.New("超出范围")))
+	es := v.Err().(Errors)
+	if got := strings.Join(es.Fields(), ","); got != "db.host,port,port" {
+		t.Errorf("fields = %s", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	var v Validator
+	v.Check(false, "a", "不能为负数")
+	v.Check(false, "b", "不能为负数")
+	b, err := json.Marshal(v.Err())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"errors":[{"field":"a","message":"不能为负数"},{"field":"b","message":"不能为负数"}]}`
+	if string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,172 @@
+package concurrent
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/**
+ * Clock 对时间相关操作的抽象
+ * 本章的演示大量使用time.Sleep等待协程执行，完整跑一遍需要将近一分钟
+ * 把Now、Sleep、After、NewTicker抽象成接口后，正常运行时使用RealClock，
+ * 测试时使用FakeClock，由测试代码手动推进时间，整个章节可以在毫秒级别内跑完
+ */
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 对*time.Ticker的抽象，C()对应time.Ticker的C字段
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// RealClock 基于time包的真实时钟
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time { return rt.t.C }
+func (rt realTicker) Stop()               { rt.t.Stop() }
+
+/**
+ * FakeClock 虚拟时钟，时间只有在调用Advance时才会前进
+ * Sleep、After和Ticker都会登记为一个等待者，Advance把时间推进到期的等待者唤醒
+ * 测试中通常在另一个协程中运行被测代码，然后通过BlockUntil等待被测代码进入等待状态，再调用Advance推进时间
+ */
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // 等待者数量变化时广播，用于BlockUntil
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	until  time.Time
+	period time.Duration // 大于0表示是Ticker
+	ch     chan time.Time
+}
+
+// NewFakeClock 创建一个从now开始的虚拟时钟
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.addWaiter(&fakeWaiter{until: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("concurrent: NewTicker的时间间隔必须大于0")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{until: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.addWaiter(w)
+	return &fakeTicker{clock: c, w: w}
+}
+
+// Advance 把时间推进d，并按到期时间的先后唤醒所有到期的等待者
+// 和time.Ticker一样，Ticker的接收方来不及读取时会丢弃多余的tick
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].until.Before(c.waiters[j].until)
+	})
+	remaining := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			remaining = append(remaining, w)
+			continue
+		}
+		select {
+		case w.ch <- w.until:
+		default:
+		}
+		if w.period > 0 {
+			for !w.until.After(c.now) {
+				w.until = w.until.Add(w.period)
+			}
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+	c.cond.Broadcast()
+}
+
+// BlockUntil 阻塞直到至少有n个等待者(包括Sleep、After和未停止的Ticker)
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiters 返回当前等待者的数量
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *FakeClock) addWaiter(w *fakeWaiter) {
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+func (c *FakeClock) removeWaiter(w *fakeWaiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, exist := range c.waiters {
+		if exist == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.cond.Broadcast()
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock *FakeClock
+	w     *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+func (t *fakeTicker) Stop()               { t.clock.removeWaiter(t.w) }
@@ -0,0 +1,73 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClockAfter(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	ch := clk.After(time.Second)
+	clk.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("未到期的After被提前唤醒")
+	default:
+	}
+	clk.Advance(time.Millisecond)
+	select {
+	case got := <-ch:
+		if want := epoch.Add(time.Second); !got.Equal(want) {
+			t.Fatalf("After返回%v, 期望%v", got, want)
+		}
+	default:
+		t.Fatal("到期的After没有被唤醒")
+	}
+	if n := clk.Waiters(); n != 0 {
+		t.Fatalf("唤醒后仍有%d个等待者", n)
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	done := make(chan struct{})
+	go func() {
+		clk.Sleep(5 * time.Second)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep没有在Advance之后返回")
+	}
+	if got := clk.Now(); !got.Equal(epoch.Add(5 * time.Second)) {
+		t.Fatalf("Now()=%v", got)
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	tk := clk.NewTicker(time.Second)
+	for i := 1; i <= 3; i++ {
+		clk.Advance(time.Second)
+		if got, want := <-tk.C(), epoch.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+			t.Fatalf("第%d次tick为%v, 期望%v", i, got, want)
+		}
+	}
+	// 接收方来不及读取时多余的tick被丢弃
+	clk.Advance(3 * time.Second)
+	<-tk.C()
+	select {
+	case <-tk.C():
+		t.Fatal("Ticker没有丢弃多余的tick")
+	default:
+	}
+	tk.Stop()
+	if n := clk.Waiters(); n != 0 {
+		t.Fatalf("Stop后仍有%d个等待者", n)
+	}
+}
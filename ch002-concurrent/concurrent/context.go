@@ -3,15 +3,20 @@ package concurrent
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
+
+	"go-practice/ch002-concurrent/tracing"
 )
 
-func ContextDemo() {
-	watchDogDemo()
-	contextWatchDogDemo()
-	contextWatchDogDemo1()
-	contextValueDemo()
+func ContextDemo(w io.Writer, clock Clock) {
+	w = newSyncWriter(w)
+	watchDogDemo(w, clock)
+	contextWatchDogDemo(w, clock)
+	contextWatchDogDemo1(w, clock)
+	watchDogComponentDemo(w, clock)
+	contextValueDemo(w)
 }
 
 /**
@@ -19,30 +24,34 @@ func ContextDemo() {
  * 如果要协程提前退出怎么办呢？可以通过select+channel的方式来解决。
  * 通过channel发送指令让监控程序停止，进而达到协程退出的目的。
  */
-func watchDogDemo() {
+func watchDogDemo(w io.Writer, clock Clock) {
 	var wg sync.WaitGroup
 	stopCh := make(chan bool) // 用来停止监控程序
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		watchDog(stopCh, "[monitor]")
+		watchDog(w, clock, stopCh, "[monitor]")
 	}()
-	time.Sleep(time.Second * 5) // 先让监控程序监控5秒
-	stopCh <- true              // 发送停止指令
+	clock.Sleep(time.Second * 5) // 先让监控程序监控5秒
+	stopCh <- true               // 发送停止指令
 	wg.Wait()
 }
-func watchDog(stopCh chan bool, name string) {
+
+// watchDog 每秒监控一次，直到从stopCh收到停止指令
+// 等待下一次监控和等待停止指令放在同一个select中，停止指令在等待期间到达也能马上退出
+func watchDog(w io.Writer, clock Clock, stopCh chan bool, name string) {
+	ticker := clock.NewTicker(time.Second * 1)
+	defer ticker.Stop()
 	// 开启for select循环，一直后台监控
 	for {
+		fmt.Fprintf(w, "%s正在监控...\n", name)
+		// ...
 		select {
 		case <-stopCh:
-			fmt.Printf("%s停止指令已收到，马上停止\n", name)
+			fmt.Fprintf(w, "%s停止指令已收到，马上停止\n", name)
 			return
-		default:
-			fmt.Printf("%s正在监控...\n", name)
-			// ...
+		case <-ticker.C():
 		}
-		time.Sleep(time.Second * 1)
 	}
 }
 
@@ -51,30 +60,33 @@ func watchDog(stopCh chan bool, name string) {
  * 这时select+channel局限就凸显出来了，即使定义多个channel解决问题，代码逻辑也会非常复杂不好维护。
  * 要解决这种复杂的协程问题，必须有一种可以跟踪协程的方案，只有跟踪到每个协程才能更好的控制它们，Go语言标准库提供了Context用来解决这类问题。
  */
-func contextWatchDogDemo() {
+func contextWatchDogDemo(w io.Writer, clock Clock) {
 	var wg sync.WaitGroup
 	ctx, stop := context.WithCancel(context.Background())
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		contextWatchDog(ctx, "[monitor]")
+		contextWatchDog(ctx, w, clock, "[monitor]")
 	}()
-	time.Sleep(time.Second * 5) // 先让监控程序监控5秒
-	stop()                      // 发送停止指令
+	clock.Sleep(time.Second * 5) // 先让监控程序监控5秒
+	stop()                       // 发送停止指令
 	wg.Wait()
 }
-func contextWatchDog(ctx context.Context, name string) {
+
+// contextWatchDog 和watchDog一样每秒监控一次，直到ctx被取消
+func contextWatchDog(ctx context.Context, w io.Writer, clock Clock, name string) {
+	ticker := clock.NewTicker(time.Second * 1)
+	defer ticker.Stop()
 	// 开启for select循环，一直后台监控
 	for {
+		fmt.Fprintf(w, "%s正在监控...\n", name)
+		// ...
 		select {
 		case <-ctx.Done():
-			fmt.Printf("%s停止指令已收到，马上停止\n", name)
+			fmt.Fprintf(w, "%s停止指令已收到，马上停止\n", name)
 			return
-		default:
-			fmt.Printf("%s正在监控...\n", name)
-			// ...
+		case <-ticker.C():
 		}
-		time.Sleep(time.Second * 1)
 	}
 }
 
@@ -134,38 +146,67 @@ func contextWatchDog(ctx context.Context, name string) {
  * 如下示例一个Context同时控制三个协程，一旦Context发出取消信号，这三个协程都会取消退出。
  * 如果一个Context有子Context，当该Context取消时，该节点下的所有子Context都会被取消。
  */
-func contextWatchDogDemo1() {
+func contextWatchDogDemo1(w io.Writer, clock Clock) {
 	var wg sync.WaitGroup
 	ctx, stop := context.WithCancel(context.Background())
 	wg.Add(3)
 	go func() {
 		defer wg.Done()
-		contextWatchDog1(ctx, "[monitor_1]")
+		contextWatchDog(ctx, w, clock, "[monitor_1]")
 	}()
 	go func() {
 		defer wg.Done()
-		contextWatchDog1(ctx, "[monitor_2]")
+		contextWatchDog(ctx, w, clock, "[monitor_2]")
 	}()
 	go func() {
 		defer wg.Done()
-		contextWatchDog1(ctx, "[monitor_3]")
+		contextWatchDog(ctx, w, clock, "[monitor_3]")
 	}()
-	time.Sleep(time.Second * 5) // 先让监控程序监控5秒
-	stop()                      // 发送停止指令
+	clock.Sleep(time.Second * 5) // 先让监控程序监控5秒
+	stop()                       // 发送停止指令
 	wg.Wait()
 }
-func contextWatchDog1(ctx context.Context, name string) {
-	// 开启for select循环，一直后台监控
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Printf("%s停止指令已收到，马上停止\n", name)
-			return
-		default:
-			fmt.Printf("%s正在监控...\n", name)
-			// ...
+
+/**
+ * 实际项目中的监控程序还要考虑更多的问题：监控多个worker、worker崩溃后自动重启、定时健康检查、查看运行状态等
+ * WatchDog(watchdog.go)把上面的监控循环整理成了一个可复用的组件，下面的示例中：
+ * 1. [monitor]就是上面的contextWatchDog，ctx取消时退出
+ * 2. [flaky]第一次运行时panic，WatchDog捕获panic后等待1秒重启它
+ * 5秒后取消ctx，WatchDog等待所有worker退出后返回，最后打印每个worker的状态
+ */
+func watchDogComponentDemo(w io.Writer, clock Clock) {
+	wd := NewWatchDog(WatchDogConfig{
+		Interval:   time.Second,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Second * 4,
+		Clock:      clock,
+		OnRestart: func(name string, err error, backoff time.Duration) {
+			fmt.Fprintf(w, "%s异常退出:%v，%v后重启\n", name, err, backoff)
+		},
+	})
+	_ = wd.Add("[monitor]", func(ctx context.Context) error {
+		contextWatchDog(ctx, w, clock, "[monitor]")
+		return nil
+	})
+	crashed := false // 只在[flaky]的协程中访问，重启前上一次运行已经返回，不存在并发访问
+	_ = wd.Add("[flaky]", func(ctx context.Context) error {
+		if !crashed {
+			crashed = true
+			panic("模拟worker崩溃")
 		}
-		time.Sleep(time.Second * 1)
+		<-ctx.Done()
+		return nil
+	})
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- wd.Run(ctx)
+	}()
+	clock.Sleep(time.Second * 5)
+	stop()
+	<-done
+	for _, s := range wd.Status() {
+		fmt.Fprintf(w, "%s state:%s restarts:%d lastError:%v\n", s.Name, s.State, s.Restarts, s.LastError)
 	}
 }
 
@@ -173,20 +214,33 @@ func contextWatchDog1(ctx context.Context, name string) {
  * Context传值
  * 通过Context还可以传递共享的数据，比如将一个请求处理或调用过程串起来，就可以在函数调用的时候传递context。
  */
-func contextValueDemo() {
+func contextValueDemo(w io.Writer) {
 	ctx := context.Background()
 	// ctx是一个空context
-	process(ctx)
-	// 通过context.WithValue函数为context赋值
-	ctx = context.WithValue(ctx, "traceId", "t_1156813585")
-	process(ctx)
+	process(ctx, w)
+	// tracing.WithTraceID内部通过context.WithValue函数为context赋值
+	// 注意不要直接使用"traceId"这样的字符串作为key，不同的包使用相同的字符串key会互相覆盖，
+	// tracing包使用未导出的类型作为key，避免了这个问题
+	ctx = tracing.WithTraceID(ctx, "t_1156813585")
+	process(ctx, w)
+	// 把ctx传递给新启动的协程，协程中开启的子span和当前调用链共享同一个trace ID
+	ctx, parent := tracing.StartSpan(ctx, "contextValueDemo")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, span := tracing.StartSpan(ctx, "process")
+		fmt.Fprintf(w, "子span的父span为contextValueDemo:%t\n", span.ParentID == parent.SpanID)
+		process(ctx, w)
+	}()
+	wg.Wait()
 }
-func process(ctx context.Context) {
-	traceId, ok := ctx.Value("traceId").(string)
+func process(ctx context.Context, w io.Writer) {
+	traceId, ok := tracing.TraceIDFrom(ctx)
 	if ok {
-		fmt.Printf("traceId:%s\n", traceId)
+		fmt.Fprintf(w, "traceId:%s\n", traceId)
 	} else {
-		fmt.Printf("no traceId\n")
+		fmt.Fprintf(w, "no traceId\n")
 	}
 }
 
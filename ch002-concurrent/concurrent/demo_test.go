@@ -0,0 +1,188 @@
+package concurrent
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer 演示结束后可能还有协程在输出，读写都需要加锁
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// step 推进虚拟时钟的一步：等到至少有waiters个等待者后把时间推进d
+type step struct {
+	waiters int
+	d       time.Duration
+}
+
+// everySecond 每秒推进一次，共n次，每一次都等待waiters个等待者
+func everySecond(n, waiters int) []step {
+	steps := make([]step, n)
+	for i := range steps {
+		steps[i] = step{waiters: waiters, d: time.Second}
+	}
+	return steps
+}
+
+// runWithFakeClock 在单独的协程中运行fn，按steps依次推进虚拟时钟，最后等待fn返回
+// 每一步都先BlockUntil等到演示中的协程全部进入等待再Advance，结果不依赖协程的调度
+func runWithFakeClock(t *testing.T, fn func(clock Clock), steps ...step) {
+	t.Helper()
+	clk := NewFakeClock(epoch)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(clk)
+	}()
+	for _, s := range steps {
+		clk.BlockUntil(s.waiters)
+		clk.Advance(s.d)
+	}
+	<-done
+	if n := clk.Waiters(); n != 0 {
+		t.Errorf("演示结束后还有%d个等待者", n)
+	}
+}
+
+// lineWriter 把每次Write的内容发送到channel中，测试可以等待某个协程的输出
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func assertContains(t *testing.T, out string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出中缺少%q\n%s", want, out)
+		}
+	}
+}
+
+func TestGoroutineDemo(t *testing.T) {
+	var out lockedBuffer
+	// goroutineDemo1 Sleep 1秒；selectDemo的3个下载各Sleep 1~9秒，一次推进9秒全部完成
+	runWithFakeClock(t, func(clock Clock) {
+		GoroutineDemo(&out, rand.New(rand.NewSource(1)), clock)
+	}, step{1, time.Second}, step{3, 9 * time.Second})
+	assertContains(t, out.String(),
+		"main goroutine",
+		"[goroutine-4]异常退出:panic: goroutine-4崩溃",
+		"接收到channel中的值为: [goroutine-1]执行完成",
+		"ch容量为:5, 元素个数为:3",
+		"firstCh:filePath", "secondCh:filePath", "threeCh:filePath",
+		"job[1] result:1\njob[2] result:4\njob[3] result:9\njob[4] err:panic: 任务4崩溃\njob[5] result:25\n",
+	)
+}
+
+func TestGoroutineDemo1(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	lines := make(lineWriter, 4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		goroutineDemo1(lines, clk)
+	}()
+	// main goroutine在Sleep期间，3个新启动的协程都能完成输出
+	got := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		got[strings.TrimSpace(<-lines)] = true
+	}
+	for _, want := range []string{"main goroutine", "goroutine-1", "goroutine-2", "goroutine-3"} {
+		if !got[want] {
+			t.Errorf("输出中缺少%q: %v", want, got)
+		}
+	}
+	clk.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("goroutineDemo1没有等待就返回了")
+	default:
+	}
+	clk.Advance(time.Second)
+	<-done
+}
+
+func TestSyncDemo(t *testing.T) {
+	if raceEnabled {
+		t.Skip("SyncDemo包含故意存在资源竞争的syncUnsafeDemo，-race下由TestUnsafeSumRace验证")
+	}
+	var out lockedBuffer
+	runWithFakeClock(t, func(clock Clock) {
+		SyncDemo(&out, clock)
+	}, step{1, 2 * time.Second})
+	got := out.String()
+	assertContains(t, got, "比赛开始，大家准备跑", "[9]号running")
+	if n := strings.Count(got, "syncOnce"); n != 1 {
+		t.Errorf("syncOnce输出了%d次", n)
+	}
+	if n := strings.Count(got, "号running"); n != 10 {
+		t.Errorf("只有%d个人起跑", n)
+	}
+}
+
+func TestContextDemo(t *testing.T) {
+	var out lockedBuffer
+	var steps []step
+	// watchDogDemo和contextWatchDogDemo：main的Sleep和[monitor]的Ticker
+	steps = append(steps, everySecond(5, 2)...)
+	steps = append(steps, everySecond(5, 2)...)
+	// contextWatchDogDemo1：main的Sleep和3个monitor的Ticker
+	steps = append(steps, everySecond(5, 4)...)
+	// watchDogComponentDemo：main的Sleep、WatchDog和[monitor]的Ticker，第1秒还有[flaky]重启前的退避
+	steps = append(steps, step{4, time.Second})
+	steps = append(steps, everySecond(4, 3)...)
+	runWithFakeClock(t, func(clock Clock) {
+		ContextDemo(&out, clock)
+	}, steps...)
+	got := out.String()
+	assertContains(t, got,
+		"[monitor_1]停止指令已收到，马上停止",
+		"[monitor_2]停止指令已收到，马上停止",
+		"[monitor_3]停止指令已收到，马上停止",
+		"[flaky]异常退出:panic: 模拟worker崩溃，1s后重启",
+		"[monitor] state:stopped restarts:0 lastError:<nil>",
+		"[flaky] state:stopped restarts:1 lastError:panic: 模拟worker崩溃",
+		"no traceId",
+		"traceId:t_1156813585",
+		"子span的父span为contextValueDemo:true",
+	)
+	if n := strings.Count(got, "[monitor]停止指令已收到，马上停止"); n != 3 {
+		t.Errorf("[monitor]收到了%d次停止指令", n)
+	}
+}
+
+func TestSyncOnceAndCond(t *testing.T) {
+	var out lockedBuffer
+	runWithFakeClock(t, func(clock Clock) {
+		syncOnceDemo(&out)
+		syncCondDemo(&out, clock)
+	}, step{1, 2 * time.Second})
+	got := out.String()
+	if n := strings.Count(got, "syncOnce"); n != 1 {
+		t.Errorf("syncOnce输出了%d次", n)
+	}
+	if n := strings.Count(got, "号running"); n != 10 {
+		t.Errorf("只有%d个人起跑", n)
+	}
+}
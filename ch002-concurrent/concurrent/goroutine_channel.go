@@ -1,7 +1,9 @@
 package concurrent
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"time"
 )
@@ -14,29 +16,46 @@ import (
  * goroutine的调度对于开发者完全透明，开发者只需要在编码时告诉Go语言要启动几个goroutine
  * 启动一个goroutine非常简单，使用go关键字加上相应的函数或方法即可
  */
-func GoroutineDemo() {
-	goroutineDemo1()
-	goroutineDemo2()
-	goroutineDemo3()
-	selectDemo()
+func GoroutineDemo(w io.Writer, rnd *rand.Rand, clock Clock) {
+	w = newSyncWriter(w)
+	goroutineDemo1(w, clock)
+	goSafeDemo(w)
+	goroutineDemo2(w)
+	goroutineDemo3(w)
+	selectDemo(w, rnd, clock)
+	poolDemo(w)
 }
 
 /**
  * 程序是并发的，go关键字启动的goroutine并不阻塞main goroutine的执行
  * time.Sleep()表示等待，不然main goroutine执行完毕程序就会退出，就看不到新启动的goroutine执行结果
  */
-func goroutineDemo1() {
+func goroutineDemo1(w io.Writer, clock Clock) {
 	go func(name string) {
-		fmt.Println(name)
+		fmt.Fprintln(w, name)
 	}("goroutine-1")
 
-	go fmt.Println("goroutine-2")
+	go fmt.Fprintln(w, "goroutine-2")
 
 	go func() {
-		fmt.Println("goroutine-3")
+		fmt.Fprintln(w, "goroutine-3")
 	}()
-	fmt.Println("main goroutine")
-	time.Sleep(time.Second)
+	fmt.Fprintln(w, "main goroutine")
+	clock.Sleep(time.Second)
+}
+
+/**
+ * 用go关键字启动的协程panic时，即使main goroutine中有recover也捕获不到，整个程序都会崩溃
+ * Go(panic.go)是go关键字的安全版本，它会recover协程中的panic，转换为*PanicError交给report处理
+ * Go返回的channel在协程结束后关闭，可以用来等待协程结束，不需要time.Sleep
+ */
+func goSafeDemo(w io.Writer) {
+	done := Go(context.Background(), func(ctx context.Context) error {
+		panic("goroutine-4崩溃")
+	}, func(err error) {
+		fmt.Fprintf(w, "[goroutine-4]异常退出:%v\n", err)
+	})
+	<-done
 }
 
 /**
@@ -50,17 +69,17 @@ func goroutineDemo1() {
  * 这里注意发送和接收的操作符都是<-，接收的<-操作符在chan的左侧，发送的<-操作符在chan的右侧
  * channel有点像在两个goroutine之间架设的管道，一个可以往这个管道里发送数据，另一个可以从这个管道取数据
  */
-func goroutineDemo2() {
+func goroutineDemo2(w io.Writer) {
 	ch := make(chan string)
 	// 启动新的goroutine向channel中发送值
 	go func() {
-		fmt.Println("goroutine-1")
+		fmt.Fprintln(w, "goroutine-1")
 		ch <- "[goroutine-1]执行完成"
 	}()
-	fmt.Println("main goroutine")
+	fmt.Fprintln(w, "main goroutine")
 	// 在main goroutine中从channel中接收值，如果channel没有值则阻塞等到channel中有值可以接收为止
 	v := <-ch
-	fmt.Println("接收到channel中的值为:", v)
+	fmt.Fprintln(w, "接收到channel中的值为:", v)
 }
 
 /**
@@ -87,16 +106,16 @@ func goroutineDemo2() {
  * onlyReceive := make(<-chan int)
  * 声明单向channel时，<-操作符的位置和上面讲到的发送和接收操作是一样的
  */
-func goroutineDemo3() {
+func goroutineDemo3(w io.Writer) {
 	ch := make(chan string, 5)
 	ch <- "a"
 	ch <- "b"
 	ch <- "c"
 	// cap可以获取channel的容量，len可以获取channel中元素的个数
-	fmt.Printf("ch容量为:%d, 元素个数为:%d\n", cap(ch), len(ch))
-	fmt.Println(<-ch)
-	fmt.Println(<-ch)
-	fmt.Println(<-ch)
+	fmt.Fprintf(w, "ch容量为:%d, 元素个数为:%d\n", cap(ch), len(ch))
+	fmt.Fprintln(w, <-ch)
+	fmt.Fprintln(w, <-ch)
+	fmt.Fprintln(w, <-ch)
 }
 
 /**
@@ -115,20 +134,26 @@ func goroutineDemo3() {
  * 整体结构和switch非常像，都有case和default，只不过select的case是一个个可以操作的channel(发送或接收)
  * 多路复用可以简单理解为在N个channel中，任意一个channel有数据产生，select都可以监听到，然后执行相应的分支接收数据并处理
  */
-func selectDemo() {
+func selectDemo(w io.Writer, rnd *rand.Rand, clock Clock) {
 	// 创建3个存放结果的channel
 	firstCh := make(chan string)
 	secondCh := make(chan string)
 	threeCh := make(chan string)
+	// *rand.Rand不是并发安全的，所以在启动goroutine之前按顺序生成每个下载的耗时，
+	// 这样同一个种子总是得到相同的耗时，也就得到相同的完成顺序
+	firstCost, secondCost, threeCost := downloadCost(rnd), downloadCost(rnd), downloadCost(rnd)
+	fmt.Fprintln(w, "firstCh", firstCost)
+	fmt.Fprintln(w, "secondCh", secondCost)
+	fmt.Fprintln(w, "threeCh", threeCost)
 	// 同时开启3个goroutine进行文件下载
 	go func() {
-		firstCh <- downloadFile("firstCh")
+		firstCh <- downloadFile(clock, "firstCh", firstCost)
 	}()
 	go func() {
-		secondCh <- downloadFile("secondCh")
+		secondCh <- downloadFile(clock, "secondCh", secondCost)
 	}()
 	go func() {
-		threeCh <- downloadFile("threeCh")
+		threeCh <- downloadFile(clock, "threeCh", threeCost)
 	}()
 	/**
 	 * 开启select多路复用，哪个channel能获取到值，就说明哪个goroutine最先执行完成
@@ -139,21 +164,25 @@ func selectDemo() {
 	for i := 0; i < 3; i++ {
 		select {
 		case filePath := <-firstCh:
-			fmt.Println(filePath)
+			fmt.Fprintln(w, filePath)
 		case filePath := <-secondCh:
-			fmt.Println(filePath)
+			fmt.Fprintln(w, filePath)
 		case filePath := <-threeCh:
-			fmt.Println(filePath)
+			fmt.Fprintln(w, filePath)
 		}
 	}
 }
 
-func downloadFile(chanName string) string {
+// downloadCost 随机生成模拟下载的耗时(秒)
+func downloadCost(rnd *rand.Rand) int {
 	min, max := 1, 10
-	n := rand.Intn(max-min) + min
-	fmt.Println(chanName, n)
+	return rnd.Intn(max-min) + min
+}
+
+// downloadFile 模拟文件下载，真实的并发下载(断点续传、校验、只取最先完成的N个)见download包
+func downloadFile(clock Clock, chanName string, n int) string {
 	// 模拟文件下载
-	time.Sleep(time.Second * time.Duration(n))
+	clock.Sleep(time.Second * time.Duration(n))
 	return chanName + ":filePath"
 }
 
@@ -162,3 +191,26 @@ func downloadFile(chanName string) string {
  * 其实就是提倡通过channel发送接收消息的方式进行数据传递，而不是通过修改同一个变量
  * 所以在数据流动、传递的场景中要优先使用channel，它是并发安全的，性能也不错
  */
+
+/**
+ * 协程池
+ * 任务很多时，为每个任务都启动一个goroutine会占用大量资源，通常会启动固定数量的goroutine(worker)，
+ * 通过一个有缓冲channel把任务分发给它们，这就是协程池，Pool(pool.go)就是这样实现的
+ * 下面的示例用3个worker计算5个数的平方，其中任务4会panic，但不会影响其他任务，结果按提交顺序输出
+ */
+func poolDemo(w io.Writer) {
+	jobs := []int{1, 2, 3, 4, 5}
+	results := Map(context.Background(), PoolConfig{Workers: 3}, jobs, func(ctx context.Context, n int) (int, error) {
+		if n == 4 {
+			panic("任务4崩溃")
+		}
+		return n * n, nil
+	})
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(w, "job[%d] err:%v\n", res.Job, res.Err)
+			continue
+		}
+		fmt.Fprintf(w, "job[%d] result:%d\n", res.Job, res.Value)
+	}
+}
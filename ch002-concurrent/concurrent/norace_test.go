@@ -0,0 +1,6 @@
+//go:build !race
+
+package concurrent
+
+// raceEnabled 测试是否在-race下运行
+const raceEnabled = false
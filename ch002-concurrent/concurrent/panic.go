@@ -0,0 +1,62 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+/**
+ * PanicError 协程中通过recover捕获到的panic
+ * 一个协程panic会导致整个程序崩溃，所以长期运行的协程需要在defer中recover，
+ * 再把panic的值和调用栈包装成error交给上层处理，而不是让程序直接退出
+ */
+type PanicError struct {
+	Value interface{} // panic时传入的值
+	Stack []byte      // recover时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// NewPanicError 包装recover得到的值，只能在defer调用的recover之后使用，这样记录的调用栈才包含panic的位置
+func NewPanicError(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+/**
+ * Go 启动一个协程运行fn，是go关键字的安全版本
+ * fn中的panic会被recover并转换为*PanicError，和fn返回的非nil错误一样交给report，不会让整个程序崩溃
+ * report为nil时忽略错误，返回的channel在fn结束(包括panic)并且report返回后关闭，可以用来等待协程结束
+ */
+func Go(ctx context.Context, fn func(ctx context.Context) error, report func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := safeCall(ctx, fn); err != nil && report != nil {
+			report(err)
+		}
+	}()
+	return done
+}
+
+// safeCall 调用fn，把fn中的panic转换为*PanicError返回
+func safeCall(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = NewPanicError(v)
+		}
+	}()
+	return fn(ctx)
+}
+
+// ReportTo 返回把错误发送到errc的report函数，用于Go的report参数，ctx结束后不再阻塞等待发送，错误被丢弃
+func ReportTo(ctx context.Context, errc chan<- error) func(error) {
+	return func(err error) {
+		select {
+		case errc <- err:
+		case <-ctx.Done():
+		}
+	}
+}
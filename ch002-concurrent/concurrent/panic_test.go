@@ -0,0 +1,50 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoRecoversPanic(t *testing.T) {
+	var got error
+	done := Go(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	}, func(err error) { got = err })
+	<-done
+	var pe *PanicError
+	if !errors.As(got, &pe) || pe.Value != "boom" {
+		t.Fatalf("report收到%v, 期望*PanicError", got)
+	}
+	// 调用栈要包含panic的位置
+	if !strings.Contains(string(pe.Stack), "TestGoRecoversPanic") {
+		t.Errorf("调用栈中缺少panic的位置:\n%s", pe.Stack)
+	}
+}
+
+func TestGoReportsError(t *testing.T) {
+	errBoom := errors.New("boom")
+	errc := make(chan error, 2)
+	ctx := context.Background()
+	<-Go(ctx, func(ctx context.Context) error { return errBoom }, ReportTo(ctx, errc))
+	<-Go(ctx, func(ctx context.Context) error { return nil }, ReportTo(ctx, errc))
+	if len(errc) != 1 || <-errc != errBoom {
+		t.Fatal("只有返回的非nil错误才应该发送到errc")
+	}
+	// report为nil时panic也不会让程序崩溃
+	<-Go(ctx, func(ctx context.Context) error { panic("ignored") }, nil)
+}
+
+func TestReportToDropsAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error) // 没有接收者
+	done := Go(ctx, func(ctx context.Context) error { return errors.New("late") }, ReportTo(ctx, errc))
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ctx结束后report仍在阻塞")
+	}
+}
@@ -0,0 +1,241 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+/**
+ * Pool 固定数量worker的协程池，把goroutine_channel.go中的channel用法整理成可复用的组件
+ * 1. Workers个协程从有缓冲的任务队列中取任务执行，队列满时Submit阻塞，从而限制了并发数和内存占用
+ * 2. 通过Context取消整个协程池，也可以为每个任务设置超时时间
+ * 3. 结果可以按完成顺序输出，也可以按提交顺序输出
+ * 4. 单个任务panic会被转换为*PanicError放入结果中，不会影响其他任务和整个协程池
+ * 使用方式：NewPool创建后，一边Submit提交任务，一边从Results读取结果，提交完所有任务后调用Close
+ * 注意Results必须被持续读取，否则worker会阻塞在发送结果上
+ */
+
+// Order 结果的输出顺序
+type Order int
+
+const (
+	CompletionOrder Order = iota // 按完成顺序输出，先完成的先输出
+	SubmissionOrder              // 按提交顺序输出，先完成的任务会等待之前提交的任务
+)
+
+// PoolConfig Pool的配置，零值字段使用默认值
+type PoolConfig struct {
+	Workers    int           // worker数量，默认runtime.NumCPU()
+	QueueSize  int           // 任务队列的容量，默认等于Workers
+	JobTimeout time.Duration // 单个任务的超时时间，0表示不限制
+	Order      Order         // 结果的输出顺序，默认CompletionOrder
+}
+
+// Result 任务的执行结果
+type Result[T, R any] struct {
+	Seq   int // 提交序号，从0开始
+	Job   T
+	Value R
+	Err   error
+
+	skip bool // 提交失败的任务占用的序号，collect跳过它，不输出
+}
+
+var ErrPoolClosed = errors.New("concurrent: Pool已经关闭")
+
+type Pool[T, R any] struct {
+	cfg     PoolConfig
+	ctx     context.Context
+	fn      func(ctx context.Context, job T) (R, error)
+	jobs    chan poolJob[T]
+	done    chan Result[T, R] // worker执行完的结果，由collect整理后输出到results
+	results chan Result[T, R]
+
+	mu      sync.Mutex // 保护closed和seq，只在检查和分配序号时持有，不会在等待队列空位时持有
+	closed  bool
+	seq     int
+	quit    chan struct{}  // Close时关闭，让阻塞在队列上的Submit返回
+	senders sync.WaitGroup // 正在向jobs发送任务的Submit，全部返回后才能关闭jobs
+}
+
+type poolJob[T any] struct {
+	seq int
+	job T
+}
+
+// NewPool 创建并启动一个协程池，ctx取消后尚未执行的任务不再执行，结果中的Err为ctx.Err()
+func NewPool[T, R any](ctx context.Context, cfg PoolConfig, fn func(ctx context.Context, job T) (R, error)) *Pool[T, R] {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.Workers
+	}
+	p := &Pool[T, R]{
+		cfg:     cfg,
+		ctx:     ctx,
+		fn:      fn,
+		jobs:    make(chan poolJob[T], cfg.QueueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan Result[T, R], cfg.Workers),
+		results: make(chan Result[T, R], cfg.Workers),
+	}
+	var wg sync.WaitGroup
+	wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range p.jobs {
+				p.done <- p.run(j)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+	go p.collect()
+	return p
+}
+
+/**
+ * Submit 提交一个任务，队列满时阻塞，直到有空位、ctx取消、协程池被取消或者被Close
+ * 多个协程并发Submit时，序号按分配的先后决定，和实际入队的先后可能不同
+ */
+func (p *Pool[T, R]) Submit(ctx context.Context, job T) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	seq := p.seq
+	p.seq++
+	p.senders.Add(1)
+	p.mu.Unlock()
+	defer p.senders.Done()
+
+	err := p.send(ctx, poolJob[T]{seq: seq, job: job})
+	if err != nil {
+		// 序号已经分配出去，通知collect跳过它，否则按提交顺序输出时会一直等待这个序号
+		// 这时jobs还没有关闭，worker还在运行，done也不会被关闭
+		p.done <- Result[T, R]{Seq: seq, skip: true}
+	}
+	return err
+}
+
+func (p *Pool[T, R]) send(ctx context.Context, j poolJob[T]) error {
+	// 队列有空位时select会在几个case中随机选择，所以先检查是否已经取消
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.jobs <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-p.quit:
+		return ErrPoolClosed
+	}
+}
+
+// Close 表示不再提交任务，阻塞在队列上的Submit返回ErrPoolClosed，已提交的任务执行完后Results会被关闭，可以重复调用
+func (p *Pool[T, R]) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.quit)
+	p.mu.Unlock()
+	// 等待正在发送的Submit返回后才能关闭jobs，否则会向已关闭的channel发送
+	p.senders.Wait()
+	close(p.jobs)
+}
+
+// Results 返回结果channel，所有任务执行完并且调用了Close之后关闭
+func (p *Pool[T, R]) Results() <-chan Result[T, R] {
+	return p.results
+}
+
+func (p *Pool[T, R]) run(j poolJob[T]) (res Result[T, R]) {
+	res = Result[T, R]{Seq: j.seq, Job: j.job}
+	if err := p.ctx.Err(); err != nil {
+		res.Err = err
+		return res
+	}
+	ctx := p.ctx
+	if p.cfg.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.JobTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			res.Err = NewPanicError(v)
+		}
+	}()
+	res.Value, res.Err = p.fn(ctx, j.job)
+	return res
+}
+
+// collect 按配置的顺序把结果输出到results
+func (p *Pool[T, R]) collect() {
+	defer close(p.results)
+	if p.cfg.Order == CompletionOrder {
+		for res := range p.done {
+			if !res.skip {
+				p.results <- res
+			}
+		}
+		return
+	}
+	// 按提交顺序输出：提前完成的结果先放在pending中，等前面的结果都输出后再输出
+	pending := make(map[int]Result[T, R])
+	next := 0
+	for res := range p.done {
+		pending[res.Seq] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if !r.skip {
+				p.results <- r
+			}
+			next++
+		}
+	}
+}
+
+// Map 使用协程池处理jobs中的所有任务，按提交顺序返回结果
+func Map[T, R any](ctx context.Context, cfg PoolConfig, jobs []T, fn func(ctx context.Context, job T) (R, error)) []Result[T, R] {
+	cfg.Order = SubmissionOrder
+	p := NewPool(ctx, cfg, fn)
+	go func() {
+		defer p.Close()
+		for _, job := range jobs {
+			// 只有ctx取消时才会提交失败，剩下的任务不再提交，结果中用ctx.Err()补齐
+			if err := p.Submit(ctx, job); err != nil {
+				return
+			}
+		}
+	}()
+	results := make([]Result[T, R], 0, len(jobs))
+	for res := range p.Results() {
+		results = append(results, res)
+	}
+	for i := len(results); i < len(jobs); i++ {
+		results = append(results, Result[T, R]{Seq: i, Job: jobs[i], Err: ctx.Err()})
+	}
+	return results
+}
@@ -0,0 +1,179 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPoolCompletionOrder(t *testing.T) {
+	// 任务0一直阻塞到任务1完成，按完成顺序输出时任务1一定在任务0之前
+	release := make(chan struct{})
+	p := NewPool(context.Background(), PoolConfig{Workers: 2}, func(ctx context.Context, n int) (int, error) {
+		if n == 0 {
+			<-release
+		}
+		return n, nil
+	})
+	go func() {
+		defer p.Close()
+		_ = p.Submit(context.Background(), 0)
+		_ = p.Submit(context.Background(), 1)
+	}()
+	var got []int
+	for res := range p.Results() {
+		got = append(got, res.Seq)
+		if res.Seq == 1 {
+			close(release)
+		}
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 0 {
+		t.Fatalf("完成顺序为%v, 期望[1 0]", got)
+	}
+}
+
+func TestPoolSubmissionOrder(t *testing.T) {
+	jobs := []int{50, 40, 30, 20, 10}
+	results := Map(context.Background(), PoolConfig{Workers: 5}, jobs, func(ctx context.Context, ms int) (int, error) {
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return ms, nil
+	})
+	for i, res := range results {
+		if res.Seq != i || res.Value != jobs[i] || res.Err != nil {
+			t.Fatalf("results[%d]=%+v", i, res)
+		}
+	}
+}
+
+func TestPoolPanicIsolation(t *testing.T) {
+	results := Map(context.Background(), PoolConfig{Workers: 1}, []int{1, 2, 3}, func(ctx context.Context, n int) (int, error) {
+		if n == 2 {
+			panic("boom")
+		}
+		return n, nil
+	})
+	var pe *PanicError
+	if !errors.As(results[1].Err, &pe) || pe.Value != "boom" {
+		t.Fatalf("任务2的错误为%v, 期望*PanicError", results[1].Err)
+	}
+	if results[0].Value != 1 || results[2].Value != 3 {
+		t.Fatalf("panic影响了其他任务: %+v", results)
+	}
+}
+
+func TestPoolJobTimeout(t *testing.T) {
+	results := Map(context.Background(), PoolConfig{Workers: 1, JobTimeout: 10 * time.Millisecond}, []int{0}, func(ctx context.Context, n int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, 期望context.DeadlineExceeded", results[0].Err)
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	p := NewPool(ctx, PoolConfig{Workers: 1, QueueSize: 3}, func(ctx context.Context, n int) (int, error) {
+		if n == 0 {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return n, nil
+	})
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	cancel()
+	if err := p.Submit(context.Background(), 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后Submit返回%v", err)
+	}
+	p.Close()
+	if err := p.Submit(context.Background(), 4); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("关闭后Submit返回%v", err)
+	}
+	var seqs []int
+	for res := range p.Results() {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("任务%d的错误为%v, 期望context.Canceled", res.Seq, res.Err)
+		}
+		seqs = append(seqs, res.Seq)
+	}
+	sort.Ints(seqs)
+	if len(seqs) != 3 {
+		t.Fatalf("得到%d个结果, 期望3个", len(seqs))
+	}
+}
+
+func TestPoolCloseWhileSubmitBlocked(t *testing.T) {
+	release := make(chan struct{})
+	p := NewPool(context.Background(), PoolConfig{Workers: 1, QueueSize: 1, Order: SubmissionOrder}, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	})
+	// 任务0被worker取走，任务1占满队列，任务2阻塞在队列上
+	_ = p.Submit(context.Background(), 0)
+	_ = p.Submit(context.Background(), 1)
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- p.Submit(context.Background(), 2)
+	}()
+
+	// Submit等待队列空位时不持有锁，Close不会被阻塞
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	if err := <-blocked; !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("阻塞的Submit返回%v, 期望ErrPoolClosed", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close被阻塞的Submit卡住")
+	}
+
+	close(release)
+	var got []int
+	for res := range p.Results() {
+		got = append(got, res.Seq)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Fatalf("结果序号为%v, 期望[0 1]", got)
+	}
+}
+
+func TestPoolSubmitCanceledKeepsOrder(t *testing.T) {
+	release := make(chan struct{})
+	p := NewPool(context.Background(), PoolConfig{Workers: 1, QueueSize: 1, Order: SubmissionOrder}, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	})
+	_ = p.Submit(context.Background(), 0)
+	_ = p.Submit(context.Background(), 1)
+	// 序号2的任务因ctx超时没有提交成功，按提交顺序输出时不能一直等待它
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Submit返回%v", err)
+	}
+	close(release)
+	if err := p.Submit(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	var got []int
+	for res := range p.Results() {
+		got = append(got, res.Job)
+	}
+	if len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 3 {
+		t.Fatalf("结果为%v, 期望[0 1 3]", got)
+	}
+}
@@ -2,6 +2,7 @@ package concurrent
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"sync"
 	"time"
@@ -11,13 +12,14 @@ import (
  * channel为什么是并发安全的呢？是因为channel内部使用了互斥锁来保证并发的安全
  * 在Go语言中，不仅有channel这类比较易用且高级的同步机制，还有sync.Mutex、sync.WaitGroup等比较原始的同步机制
  */
-func SyncDemo() {
-	syncUnsafeDemo()
-	syncMutexDemo()
-	syncRWMutex()
-	syncWaitGroup()
-	syncOnceDemo()
-	syncCondDemo()
+func SyncDemo(w io.Writer, clock Clock) {
+	w = newSyncWriter(w)
+	syncUnsafeDemo(w)
+	syncMutexDemo(w)
+	syncRWMutex(w)
+	syncWaitGroup(w)
+	syncOnceDemo(w)
+	syncCondDemo(w, clock)
 }
 
 /**
@@ -27,41 +29,57 @@ func SyncDemo() {
  * 如下示例，期待的结果是1000，但很可能出现990或980等
  * 导致这种情况的核心原因是资源sum不是并发安全的，因为同时会有多个协程交叉执行sum+=i，产生不可预料的结果
  * 使用go build、go run、go test这些Go语言工具链提供的命令时，添加-race标识可以帮你检查Go语言代码是否存在资源竞争
+ * sync_test.go中的TestUnsafeSumRace就是在-race下运行unsafeSum，验证它确实会被检查出资源竞争
+ *
+ * 以下几个示例都把累加的过程放在单独的函数中并返回结果，便于测试验证
+ * 等待协程执行完毕使用的是sync.WaitGroup(详见下文的syncWaitGroup)，相比time.Sleep等待固定的时间，它能准确地在所有协程执行完毕时返回
  */
-func syncUnsafeDemo() {
+func syncUnsafeDemo(w io.Writer) {
+	fmt.Fprintln(w, unsafeSum())
+}
+
+// unsafeSum 开启100个协程让sum+10，没有加锁，存在资源竞争
+func unsafeSum() int {
 	// 共享的资源
 	sum := 0
-	// 开启100个协程让sum+10
+	var wg sync.WaitGroup
+	wg.Add(100)
 	for i := 0; i < 100; i++ {
 		go func() {
+			defer wg.Done()
 			sum += 10
 		}()
 	}
-	// 防止主协程提前退出
-	time.Sleep(time.Second * 2)
-	fmt.Println(sum)
+	wg.Wait()
+	return sum
 }
 
 /**
  * sync.Mutex
  * 互斥锁，指的是在同一时刻只有一个协程执行某段代码，其他协程都要等待该协程执行完毕后才能继续执行
  */
-func syncMutexDemo() {
+func syncMutexDemo(w io.Writer) {
+	fmt.Fprintln(w, mutexSum())
+}
+
+func mutexSum() int {
 	var (
 		sum   = 0
 		mutex sync.Mutex
+		wg    sync.WaitGroup
 	)
+	wg.Add(100)
 	for i := 0; i < 100; i++ {
 		go func() {
+			defer wg.Done()
 			// 加锁Lock，解锁Unlock，defer语句确保锁一定会被释放
 			mutex.Lock()
 			defer mutex.Unlock()
 			sum += 10
 		}()
 	}
-	// 防止主协程提前退出
-	time.Sleep(time.Second * 2)
-	fmt.Println(sum)
+	wg.Wait()
+	return sum
 }
 
 /**
@@ -69,14 +87,22 @@ func syncMutexDemo() {
  * 读写锁，该锁可以加多个读锁或者一个写锁，适用于读多写少的场景
  * sync.RWMutex比sync.Mutex性能要高，因为多个goroutine可以同时读数据，不再相互等待
  */
-func syncRWMutex() {
+func syncRWMutex(w io.Writer) {
+	fmt.Fprintln(w, rwMutexSum(w))
+}
+
+// rwMutexSum 写协程累加sum，同时读协程把读到的中间结果输出到w
+func rwMutexSum(w io.Writer) int {
 	var (
 		sum   = 0
 		mutex sync.RWMutex
+		wg    sync.WaitGroup
 	)
+	wg.Add(110)
 	// write
 	for i := 0; i < 100; i++ {
 		go func() {
+			defer wg.Done()
 			mutex.Lock()
 			defer mutex.Unlock()
 			sum += 10
@@ -85,15 +111,15 @@ func syncRWMutex() {
 	// read
 	for i := 0; i < 10; i++ {
 		go func() {
+			defer wg.Done()
 			// 只获取读锁
 			mutex.RLock()
 			defer mutex.RUnlock()
-			fmt.Println(sum)
+			fmt.Fprintln(w, sum)
 		}()
 	}
-	// 防止主协程提前退出
-	time.Sleep(time.Second * 2)
-	fmt.Println(sum)
+	wg.Wait()
+	return sum
 }
 
 /**
@@ -106,7 +132,11 @@ func syncRWMutex() {
  * 3. 最后调用Wait方法一直等待，直到计数器值为0，也就是所有跟踪的协程都执行完毕
  * 通过sync.WaitGroup可以很好地跟踪协程，在其他协程执行完毕后，主协程函数才能执行完毕
  */
-func syncWaitGroup() {
+func syncWaitGroup(w io.Writer) {
+	fmt.Fprintln(w, waitGroupSum())
+}
+
+func waitGroupSum() int {
 	var (
 		sum   = 0
 		mutex sync.RWMutex
@@ -126,7 +156,7 @@ func syncWaitGroup() {
 	}
 	// 一直等待直到所有协程执行完毕
 	wg.Wait()
-	fmt.Println(sum)
+	return sum
 }
 
 /**
@@ -134,7 +164,7 @@ func syncWaitGroup() {
  * 在实际的工作中可能会有这样的需求，希望代码只执行一次，Go语言为我们提供了sync.Once来保证代码只执行一次
  * sync.Once适用于创建某个对象的单例、只加载一次的资源等只执行一次的场景
  */
-func syncOnceDemo() {
+func syncOnceDemo(w io.Writer) {
 	var (
 		once sync.Once
 		wg   sync.WaitGroup
@@ -144,10 +174,10 @@ func syncOnceDemo() {
 	for i := 0; i < goroutineNum; i++ {
 		go func(i int) {
 			defer wg.Done()
-			fmt.Println("goroutine_" + strconv.Itoa(i))
+			fmt.Fprintln(w, "goroutine_"+strconv.Itoa(i))
 			// 保证代码执行一次
 			once.Do(func() {
-				fmt.Println("syncOnce")
+				fmt.Fprintln(w, "syncOnce")
 			})
 		}(i)
 	}
@@ -161,26 +191,32 @@ func syncOnceDemo() {
  * sync.Cond从字面意思看是条件变量，它具有阻塞协程和唤醒协程的功能，所以可以在满足一定条件的情况下唤醒协程，但条件变量只是它的一种使用场景
  * 下面以10个人赛跑为例来演示sync.Cond的用法，在示例中有1个裁判，裁判要先等这10个人准备就绪，然后一声发令枪响，这10个人就可以开始跑了
  */
-func syncCondDemo() {
+func syncCondDemo(w io.Writer, clock Clock) {
 	cond := sync.NewCond(&sync.Mutex{})
+	started := false // 发令枪是否已经响过，由cond.L保护
 	var wg sync.WaitGroup
 	wg.Add(11)
 	for i := 0; i < 10; i++ {
 		go func(num int) {
 			defer wg.Done()
-			fmt.Printf("[%d]号已经就位\n", num)
+			fmt.Fprintf(w, "[%d]号已经就位\n", num)
 			cond.L.Lock()
-			cond.Wait() //使当前协程进入等待状态而不结束，直到在其他协程中被唤醒通知到然后继续执行完成
+			for !started {
+				cond.Wait() //使当前协程进入等待状态而不结束，直到在其他协程中被唤醒通知到然后继续执行完成
+			}
 			cond.L.Unlock()
-			fmt.Printf("[%d]号running\n", num)
+			fmt.Fprintf(w, "[%d]号running\n", num)
 		}(i)
 	}
 	// 等待所有goroutine都进入wait状态
-	time.Sleep(time.Second * 2)
+	clock.Sleep(time.Second * 2)
 	go func() {
 		defer wg.Done()
-		fmt.Println("裁判已经就位，准备发令枪")
-		fmt.Println("比赛开始，大家准备跑")
+		fmt.Fprintln(w, "裁判已经就位，准备发令枪")
+		fmt.Fprintln(w, "比赛开始，大家准备跑")
+		cond.L.Lock()
+		started = true
+		cond.L.Unlock()
 		cond.Broadcast() // 通知其他协程继续执行
 	}()
 	wg.Wait()
@@ -191,7 +227,9 @@ func syncCondDemo() {
  * 1. 通过sync.NewCond函数生成一个*sync.Cond，用于阻塞和唤醒协程
  * 2. 然后启动10个协程模拟10个人，准备就位后调用cond.Wait()方法阻塞当前协程等待发令枪响，这里需要注意的是调用cond.Wait()方法时要加锁
  * 3. time.Sleep用于等待所有人都进入wait阻塞状态，这样裁判才能调用cond.Broadcast()发号施令
- * 4. 裁判准备完毕后，就可以调用cond.Broadcast()通知所有人开始跑了
+ * 4. 裁判准备完毕后，把started置为true，再调用cond.Broadcast()通知所有人开始跑了
+ * 5. cond.Wait()要放在检查条件的for循环中，如果某个人还没来得及进入wait状态发令枪就响了，
+ *    他检查到started已经为true就不会再等待，否则会错过这次Broadcast一直阻塞下去
  * sync.Cond有三个方法，它们分别是：
  * 1. wait，阻塞当前协程，直到被其他协程调用Broadcast或者Signal方法唤醒，使用的时候需要加锁，使用sync.Cond中的锁即可，也就是L字段
  * 2. Signal，唤醒一个等待时间最长的协程
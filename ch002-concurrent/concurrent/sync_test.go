@@ -0,0 +1,53 @@
+package concurrent
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// 安全的版本无论运行多少次都是1000，在-race下运行时，如果存在资源竞争测试会直接失败
+func TestSafeSums(t *testing.T) {
+	sums := map[string]func() int{
+		"mutexSum":     mutexSum,
+		"rwMutexSum":   func() int { return rwMutexSum(io.Discard) },
+		"waitGroupSum": waitGroupSum,
+	}
+	for name, sum := range sums {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := sum(); got != 1000 {
+					t.Fatalf("第%d次运行得到%d, 期望1000", i, got)
+				}
+			}
+		})
+	}
+}
+
+// raceHelperEnv 设置后TestUnsafeSumHelper才会运行unsafeSum
+const raceHelperEnv = "GO_PRACTICE_RACE_HELPER"
+
+// 资源竞争被检查出来时测试会失败，所以在子进程中运行unsafeSum，再检查子进程的输出
+func TestUnsafeSumRace(t *testing.T) {
+	if !raceEnabled {
+		t.Skip("需要使用-race运行: go test -race ./ch002-concurrent/concurrent")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUnsafeSumHelper$", "-test.count=1")
+	cmd.Env = append(os.Environ(), raceHelperEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("unsafeSum没有被检查出资源竞争\n%s", out)
+	}
+	if !strings.Contains(string(out), "WARNING: DATA RACE") {
+		t.Fatalf("子进程失败但没有报告资源竞争: %v\n%s", err, out)
+	}
+}
+
+func TestUnsafeSumHelper(t *testing.T) {
+	if os.Getenv(raceHelperEnv) == "" {
+		t.Skip("只在TestUnsafeSumRace的子进程中运行")
+	}
+	unsafeSum()
+}
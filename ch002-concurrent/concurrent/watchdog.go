@@ -0,0 +1,263 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/**
+ * WatchDog 把context.go中watchDog/contextWatchDog的监控循环整理成一个可复用的组件
+ * 1. 同时看护多个有名字的worker，每个worker运行在自己的协程中
+ * 2. worker panic或者返回error时，按指数退避的间隔重启；返回nil表示正常结束，不再重启
+ * 3. 按Interval定时对运行中的worker做健康检查，检查失败时取消该worker的ctx，等它退出后按退避间隔重启
+ * 4. 通过Context优雅停止：取消ctx后，Run会等待所有worker退出再返回
+ * 5. Status随时返回所有worker的状态快照
+ */
+
+// WorkerFunc 被看护的worker，ctx取消时应尽快返回
+type WorkerFunc func(ctx context.Context) error
+
+// WorkerState worker的运行状态
+type WorkerState string
+
+const (
+	WorkerPending    WorkerState = "pending"    // 还没有启动
+	WorkerRunning    WorkerState = "running"    // 正在运行
+	WorkerRestarting WorkerState = "restarting" // 异常退出，等待重启
+	WorkerFinished   WorkerState = "finished"   // 返回nil，正常结束
+	WorkerStopped    WorkerState = "stopped"    // 因ctx取消而停止
+)
+
+// WorkerStatus worker的状态快照
+type WorkerStatus struct {
+	Name      string
+	State     WorkerState
+	Healthy   bool      // 最近一次健康检查是否通过
+	Restarts  int       // 重启次数
+	LastError error     // 最近一次异常退出的原因，panic时为*PanicError
+	LastCheck time.Time // 最近一次健康检查的时间
+	StartedAt time.Time // 最近一次启动的时间
+}
+
+// WatchDogConfig WatchDog的配置，零值字段使用默认值
+type WatchDogConfig struct {
+	Interval    time.Duration                                // 健康检查间隔，默认1秒
+	HealthCheck func(ctx context.Context, name string) error // 健康检查，返回error时重启worker，为nil时运行中即视为健康
+	MinBackoff  time.Duration                                // 第一次重启前的等待时间，默认100毫秒
+	MaxBackoff  time.Duration                                // 重启等待时间的上限，默认30秒
+	Clock       Clock                                        // 默认RealClock
+	// OnRestart 在worker异常退出、准备重启时调用，可用于记录日志
+	OnRestart func(name string, err error, backoff time.Duration)
+}
+
+var (
+	ErrWatchDogRunning = errors.New("concurrent: WatchDog已经在运行")
+	ErrDuplicateWorker = errors.New("concurrent: worker名称重复")
+	ErrWorkerUnhealthy = errors.New("concurrent: worker健康检查失败")
+)
+
+type WatchDog struct {
+	cfg     WatchDogConfig
+	mu      sync.Mutex
+	running bool
+	workers []*watchedWorker
+}
+
+type watchedWorker struct {
+	name   string
+	fn     WorkerFunc
+	status WorkerStatus            // 由WatchDog.mu保护
+	cancel context.CancelCauseFunc // 取消本次运行，由WatchDog.mu保护
+}
+
+// NewWatchDog 创建一个WatchDog，使用Add添加worker后调用Run启动
+func NewWatchDog(cfg WatchDogConfig) *WatchDog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = RealClock
+	}
+	return &WatchDog{cfg: cfg}
+}
+
+// Add 添加一个worker，只能在Run之前调用
+func (wd *WatchDog) Add(name string, fn WorkerFunc) error {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if wd.running {
+		return ErrWatchDogRunning
+	}
+	for _, w := range wd.workers {
+		if w.name == name {
+			return fmt.Errorf("%w: %s", ErrDuplicateWorker, name)
+		}
+	}
+	wd.workers = append(wd.workers, &watchedWorker{
+		name:   name,
+		fn:     fn,
+		status: WorkerStatus{Name: name, State: WorkerPending},
+	})
+	return nil
+}
+
+// Run 启动所有worker和健康检查，阻塞直到ctx取消或所有worker都正常结束
+// ctx取消时会等待所有worker退出后再返回ctx.Err()，所有worker正常结束时返回nil
+func (wd *WatchDog) Run(ctx context.Context) error {
+	wd.mu.Lock()
+	if wd.running {
+		wd.mu.Unlock()
+		return ErrWatchDogRunning
+	}
+	wd.running = true
+	workers := append([]*watchedWorker(nil), wd.workers...)
+	wd.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(len(workers))
+	for _, w := range workers {
+		go func(w *watchedWorker) {
+			defer wg.Done()
+			wd.supervise(ctx, w)
+		}(w)
+	}
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+
+	ticker := wd.cfg.Clock.NewTicker(wd.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-allDone:
+			return ctx.Err()
+		case <-ctx.Done():
+			<-allDone
+			return ctx.Err()
+		case <-ticker.C():
+			wd.checkHealth(ctx, workers)
+		}
+	}
+}
+
+// Status 返回所有worker的状态快照，顺序和Add的顺序一致
+func (wd *WatchDog) Status() []WorkerStatus {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	statuses := make([]WorkerStatus, 0, len(wd.workers))
+	for _, w := range wd.workers {
+		statuses = append(statuses, w.status)
+	}
+	return statuses
+}
+
+// supervise 运行worker，异常退出时按指数退避重启，直到worker正常结束或ctx取消
+func (wd *WatchDog) supervise(ctx context.Context, w *watchedWorker) {
+	backoff := wd.cfg.MinBackoff
+	for {
+		startedAt := wd.cfg.Clock.Now()
+		// 每次运行使用单独的ctx，健康检查失败时只取消这一次运行
+		runCtx, cancelRun := context.WithCancelCause(ctx)
+		wd.mu.Lock()
+		w.cancel = cancelRun
+		w.status.State = WorkerRunning
+		w.status.Healthy = true
+		w.status.StartedAt = startedAt
+		wd.mu.Unlock()
+		err := runWorker(runCtx, w.fn)
+		cause := context.Cause(runCtx)
+		cancelRun(nil)
+		if ctx.Err() != nil {
+			wd.update(w, func(s *WorkerStatus) { s.State, s.Healthy = WorkerStopped, false })
+			return
+		}
+		// 因健康检查失败被取消的worker，不管返回什么都要重启
+		if errors.Is(cause, ErrWorkerUnhealthy) {
+			err = cause
+		}
+		if err == nil {
+			wd.update(w, func(s *WorkerStatus) { s.State, s.Healthy = WorkerFinished, false })
+			return
+		}
+		// 运行时间超过MaxBackoff说明worker已经稳定运行过一段时间，退避时间从头开始计算
+		if wd.cfg.Clock.Now().Sub(startedAt) >= wd.cfg.MaxBackoff {
+			backoff = wd.cfg.MinBackoff
+		}
+		wd.update(w, func(s *WorkerStatus) {
+			s.State, s.Healthy = WorkerRestarting, false
+			s.LastError = err
+		})
+		if wd.cfg.OnRestart != nil {
+			wd.cfg.OnRestart(w.name, err, backoff)
+		}
+		select {
+		case <-ctx.Done():
+			wd.update(w, func(s *WorkerStatus) { s.State = WorkerStopped })
+			return
+		case <-wd.cfg.Clock.After(backoff):
+		}
+		wd.update(w, func(s *WorkerStatus) { s.Restarts++ })
+		if backoff *= 2; backoff > wd.cfg.MaxBackoff {
+			backoff = wd.cfg.MaxBackoff
+		}
+	}
+}
+
+// runWorker 运行一次worker，把panic转换为*PanicError
+func runWorker(ctx context.Context, fn WorkerFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = NewPanicError(p)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (wd *WatchDog) checkHealth(ctx context.Context, workers []*watchedWorker) {
+	now := wd.cfg.Clock.Now()
+	for _, w := range workers {
+		wd.mu.Lock()
+		state, cancel := w.status.State, w.cancel
+		wd.mu.Unlock()
+		if state != WorkerRunning {
+			continue
+		}
+		var err error
+		if wd.cfg.HealthCheck != nil {
+			err = wd.cfg.HealthCheck(ctx, w.name)
+		}
+		wd.update(w, func(s *WorkerStatus) {
+			// 健康检查期间worker可能已经退出，只更新仍在运行的worker
+			if s.State == WorkerRunning {
+				s.Healthy = err == nil
+			}
+			s.LastCheck = now
+		})
+		if err != nil {
+			// 取消的是检查前那一次运行，期间worker已经重启时不会影响新的运行
+			cancel(fmt.Errorf("%w: %w", ErrWorkerUnhealthy, err))
+		}
+	}
+}
+
+func (wd *WatchDog) update(w *watchedWorker, fn func(s *WorkerStatus)) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	fn(&w.status)
+}
@@ -0,0 +1,179 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startWatchDog 在新协程中运行wd，返回取消函数和Run的结果
+func startWatchDog(wd *WatchDog) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- wd.Run(ctx)
+	}()
+	return cancel, done
+}
+
+func TestWatchDogBackoff(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	backoffs := make(chan time.Duration, 1)
+	wd := NewWatchDog(WatchDogConfig{
+		Interval:   time.Hour, // 测试期间不做健康检查
+		MinBackoff: time.Second,
+		MaxBackoff: 4 * time.Second,
+		Clock:      clk,
+		OnRestart: func(name string, err error, backoff time.Duration) {
+			backoffs <- backoff
+		},
+	})
+	errFail := errors.New("fail")
+	runs := 0 // 只在worker的协程中访问，重启前上一次运行已经返回
+	_ = wd.Add("flaky", func(ctx context.Context) error {
+		switch runs++; runs {
+		case 5:
+			// 稳定运行超过MaxBackoff后才失败
+			clk.Sleep(5 * time.Second)
+			return errFail
+		case 6:
+			return nil
+		}
+		return errFail
+	})
+	_, done := startWatchDog(wd)
+
+	// 每次失败退避时间翻倍，不超过MaxBackoff；第5次运行了5秒，退避时间重新从MinBackoff开始
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, time.Second}
+	for i, w := range want {
+		if i == 4 {
+			// 等待第5次运行中的Sleep
+			clk.BlockUntil(2)
+			clk.Advance(5 * time.Second)
+		}
+		if got := <-backoffs; got != w {
+			t.Fatalf("第%d次重启的退避时间为%v, 期望%v", i+1, got, w)
+		}
+		// 等待Ticker和退避的After
+		clk.BlockUntil(2)
+		clk.Advance(w)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("所有worker正常结束时Run返回%v", err)
+	}
+	s := wd.Status()[0]
+	if s.State != WorkerFinished || s.Restarts != 5 || !errors.Is(s.LastError, errFail) {
+		t.Fatalf("status: %+v", s)
+	}
+}
+
+func TestWatchDogPanic(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	restarted := make(chan error, 1)
+	wd := NewWatchDog(WatchDogConfig{Interval: time.Hour, Clock: clk, OnRestart: func(name string, err error, backoff time.Duration) {
+		restarted <- err
+	}})
+	panicked := false
+	_ = wd.Add("worker", func(ctx context.Context) error {
+		if !panicked {
+			panicked = true
+			panic("boom")
+		}
+		return nil
+	})
+	_, done := startWatchDog(wd)
+	var pe *PanicError
+	if err := <-restarted; !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("OnRestart err=%v, 期望*PanicError", err)
+	}
+	clk.BlockUntil(2)
+	clk.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchDogHealthCheck(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	var unhealthy atomic.Bool
+	errDB := errors.New("db down")
+	restarted := make(chan error, 1)
+	wd := NewWatchDog(WatchDogConfig{
+		Interval:   time.Second,
+		MinBackoff: time.Second,
+		Clock:      clk,
+		HealthCheck: func(ctx context.Context, name string) error {
+			if unhealthy.Load() {
+				return errDB
+			}
+			return nil
+		},
+		OnRestart: func(name string, err error, backoff time.Duration) {
+			restarted <- err
+		},
+	})
+	started := make(chan struct{}, 1)
+	_ = wd.Add("worker", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		// 被取消时返回nil，WatchDog仍然要重启它
+		return nil
+	})
+	cancel, done := startWatchDog(wd)
+	<-started
+
+	unhealthy.Store(true)
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	if err := <-restarted; !errors.Is(err, ErrWorkerUnhealthy) || !errors.Is(err, errDB) {
+		t.Fatalf("OnRestart err=%v", err)
+	}
+	if s := wd.Status()[0]; s.State != WorkerRestarting || s.Healthy {
+		t.Fatalf("健康检查失败后的status: %+v", s)
+	}
+
+	unhealthy.Store(false)
+	clk.BlockUntil(2)
+	clk.Advance(time.Second)
+	<-started
+	s := wd.Status()[0]
+	if s.State != WorkerRunning || s.Restarts != 1 || !errors.Is(s.LastError, ErrWorkerUnhealthy) {
+		t.Fatalf("重启后的status: %+v", s)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run返回%v", err)
+	}
+	if s := wd.Status()[0]; s.State != WorkerStopped {
+		t.Fatalf("停止后的status: %+v", s)
+	}
+}
+
+func TestWatchDogErrors(t *testing.T) {
+	wd := NewWatchDog(WatchDogConfig{Clock: NewFakeClock(epoch)})
+	started := make(chan struct{})
+	if err := wd.Add("a", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wd.Add("a", nil); !errors.Is(err, ErrDuplicateWorker) {
+		t.Fatalf("重复的名称: %v", err)
+	}
+
+	cancel, done := startWatchDog(wd)
+	<-started
+	if err := wd.Add("b", nil); !errors.Is(err, ErrWatchDogRunning) {
+		t.Fatalf("运行中Add: %v", err)
+	}
+	if err := wd.Run(context.Background()); !errors.Is(err, ErrWatchDogRunning) {
+		t.Fatalf("重复Run: %v", err)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,28 @@
+package concurrent
+
+import (
+	"io"
+	"sync"
+)
+
+/**
+ * 本章的演示会在多个协程中同时输出，而bytes.Buffer这类io.Writer并不是并发安全的
+ * 所以导出的演示入口都会先用syncWriter包装一下传入的w，保证每次写入都是互斥的
+ */
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newSyncWriter(w io.Writer) io.Writer {
+	if sw, ok := w.(*syncWriter); ok {
+		return sw
+	}
+	return &syncWriter{w: w}
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
@@ -0,0 +1,474 @@
+/**
+ * download包是selectDemo中模拟的downloadFile的真实版本
+ * 1. 基于concurrent.Pool并发下载多个文件，并限制同时下载的数量
+ * 2. 下载过程中通过回调报告每个文件的进度
+ * 3. 下载中的数据先写入"目标文件.part"，中断后再次下载时通过Range请求从断点处继续，
+ *    服务端返回的ETag或Last-Modified保存在"目标文件.part.validator"中，续传时通过If-Range确认服务端的文件没有变化
+ * 4. 下载完成后校验sha256，通过后才重命名为目标文件
+ * 5. First只等待最先完成的N个文件，然后取消其他下载，对应selectDemo中"哪个先完成就用哪个"的场景
+ */
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-practice/ch002-concurrent/concurrent"
+)
+
+// partSuffix 未下载完成的文件后缀
+const partSuffix = ".part"
+
+// validatorSuffix 保存.part文件对应的ETag或Last-Modified的文件后缀，加在.part文件名之后
+const validatorSuffix = ".validator"
+
+var (
+	ErrChecksumMismatch = errors.New("download: sha256校验失败")
+	ErrNotEnough        = errors.New("download: 成功下载的文件数量不足")
+	ErrDuplicatePath    = errors.New("download: 多个任务保存到同一个文件")
+)
+
+// Task 一个下载任务
+type Task struct {
+	URL    string
+	Path   string // 保存路径，为空时保存到Downloader.Dir下，文件名取URL路径的最后一段
+	SHA256 string // 期望的sha256(十六进制)，为空时不校验
+}
+
+// Progress 下载进度
+type Progress struct {
+	URL        string
+	Downloaded int64 // 已下载的字节数，包括之前下载的部分
+	Total      int64 // 文件总字节数，未知时为-1
+}
+
+// Result 下载结果
+type Result struct {
+	Task    Task
+	Path    string // 最终的保存路径
+	Size    int64
+	SHA256  string
+	Resumed bool // 是否从断点续传
+	Err     error
+}
+
+// Downloader 并发下载器，零值可以直接使用，不能复制
+type Downloader struct {
+	Client      *http.Client   // 默认http.DefaultClient
+	Dir         string         // Task.Path为空时的保存目录，默认当前目录
+	Concurrency int            // 同时下载的文件数量，默认4
+	OnProgress  func(Progress) // 进度回调，会在多个协程中并发调用
+
+	mu     sync.Mutex
+	active map[string]bool // 正在下载的保存路径，防止两个下载同时写同一个.part文件
+}
+
+/**
+ * Download 并发下载所有文件，按tasks的顺序返回结果
+ * 和前面的任务保存到同一个文件的任务不会执行，结果的Err为ErrDuplicatePath
+ */
+func (d *Downloader) Download(ctx context.Context, tasks []Task) []Result {
+	dups := d.duplicates(tasks)
+	// 按下标执行，这样可以跳过重复的任务
+	idx := make([]int, len(tasks))
+	for i := range idx {
+		idx[i] = i
+	}
+	results := concurrent.Map(ctx, d.poolConfig(), idx, func(ctx context.Context, i int) (Result, error) {
+		if dups[i] != nil {
+			return Result{Task: tasks[i], Path: d.destPath(tasks[i]), Err: dups[i]}, dups[i]
+		}
+		res := d.Fetch(ctx, tasks[i])
+		return res, res.Err
+	})
+	out := make([]Result, len(results))
+	for i, r := range results {
+		out[i] = r.Value
+		// 任务因为ctx取消没有执行或者panic时，Value是零值
+		out[i].Task = tasks[i]
+		if r.Err != nil && out[i].Err == nil {
+			out[i].Err = r.Err
+		}
+	}
+	return out
+}
+
+// duplicates 返回每个任务的重复错误，保存路径和前面的任务相同时不为nil
+func (d *Downloader) duplicates(tasks []Task) []error {
+	errs := make([]error, len(tasks))
+	seen := make(map[string]string, len(tasks))
+	for i, t := range tasks {
+		p := d.destPath(t)
+		if p == "" {
+			continue
+		}
+		key := pathKey(p)
+		if first, ok := seen[key]; ok {
+			errs[i] = fmt.Errorf("%w: %s和%s都保存到%s", ErrDuplicatePath, first, t.URL, p)
+			continue
+		}
+		seen[key] = t.URL
+	}
+	return errs
+}
+
+// pathKey 用于比较两个保存路径是否是同一个文件
+func pathKey(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// acquire 标记path正在下载，path已经在下载时返回ErrDuplicatePath
+func (d *Downloader) acquire(p string) (release func(), err error) {
+	key := pathKey(p)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active[key] {
+		return nil, fmt.Errorf("%w: %s正在下载", ErrDuplicatePath, p)
+	}
+	if d.active == nil {
+		d.active = make(map[string]bool)
+	}
+	d.active[key] = true
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.active, key)
+	}, nil
+}
+
+/**
+ * First 同时开始下载所有文件，返回最先成功完成的n个结果(按完成顺序)，然后取消其余的下载
+ * 被取消的下载会保留.part文件，之后可以续传
+ * 成功的数量不足n个时，返回已经成功的结果和ErrNotEnough
+ * 有多个任务保存到同一个文件时不下载，直接返回ErrDuplicatePath
+ */
+func (d *Downloader) First(ctx context.Context, tasks []Task, n int) ([]Result, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := errors.Join(d.duplicates(tasks)...); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p := concurrent.NewPool(ctx, d.poolConfig(), func(ctx context.Context, t Task) (Result, error) {
+		res := d.Fetch(ctx, t)
+		return res, res.Err
+	})
+	go func() {
+		defer p.Close()
+		for _, t := range tasks {
+			if p.Submit(ctx, t) != nil {
+				return
+			}
+		}
+	}()
+	var done []Result
+	var errs []error
+	results := p.Results()
+	for results != nil {
+		select {
+		case r, ok := <-results:
+			if !ok {
+				results = nil
+				break
+			}
+			if r.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.Job.URL, r.Err))
+				continue
+			}
+			if len(done) < n {
+				done = append(done, r.Value)
+			}
+			if len(done) == n {
+				// 已经够了，取消其他下载，继续读取结果直到Results关闭，保证所有协程退出
+				cancel()
+			}
+		case <-ctx.Done():
+			// 外部取消时同样需要读完结果
+			cancel()
+			for range results {
+			}
+			results = nil
+		}
+	}
+	if len(done) < n {
+		return done, errors.Join(append([]error{ErrNotEnough}, errs...)...)
+	}
+	return done, nil
+}
+
+func (d *Downloader) poolConfig() concurrent.PoolConfig {
+	workers := d.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	return concurrent.PoolConfig{Workers: workers}
+}
+
+/**
+ * Fetch 下载单个文件，支持断点续传和sha256校验
+ * 续传时通过If-Range带上之前保存的ETag或Last-Modified，服务端的文件变化时会返回整个文件，从头下载
+ * 之前没有保存ETag或Last-Modified时无法确认文件没有变化，只有指定了Task.SHA256(可以发现数据错误)才续传
+ * 同一个Downloader中保存路径相同的下载正在进行时返回ErrDuplicatePath
+ */
+func (d *Downloader) Fetch(ctx context.Context, t Task) Result {
+	res := Result{Task: t, Path: d.destPath(t)}
+	if res.Path == "" {
+		res.Err = fmt.Errorf("download: 无法从%q推断文件名，需要指定Task.Path", t.URL)
+		return res
+	}
+	release, err := d.acquire(res.Path)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer release()
+	part := res.Path + partSuffix
+	offset, err := partSize(part)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	validator := readValidator(part)
+	if validator == "" && t.SHA256 == "" {
+		offset = 0
+	}
+	size, start, err := d.fetch(ctx, t.URL, part, offset, validator)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		// .part文件比服务端的文件还大，说明服务端的文件变了，只能重新下载
+		if err = removePart(part); err == nil {
+			size, start, err = d.fetch(ctx, t.URL, part, 0, "")
+		}
+	}
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Resumed = start > 0
+	res.Size = size
+	if res.SHA256, err = fileSHA256(part); err != nil {
+		res.Err = err
+		return res
+	}
+	if t.SHA256 != "" && !strings.EqualFold(t.SHA256, res.SHA256) {
+		// 数据已经损坏，续传也没有意义，删掉.part文件
+		_ = removePart(part)
+		res.Err = fmt.Errorf("%w: 期望%s, 实际%s", ErrChecksumMismatch, t.SHA256, res.SHA256)
+		return res
+	}
+	if res.Err = os.Rename(part, res.Path); res.Err == nil {
+		_ = os.Remove(part + validatorSuffix)
+	}
+	return res
+}
+
+var errRangeNotSatisfiable = errors.New("download: range not satisfiable")
+
+/**
+ * fetch 从offset处开始下载，把数据追加到part文件中，返回文件的总大小和实际续传的位置
+ * 服务端不支持Range或者文件已经变化(If-Range不匹配)时返回整个文件，从头下载，续传的位置为0
+ */
+func (d *Downloader) fetch(ctx context.Context, rawURL, part string, offset int64, validator string) (size, start int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		from, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, 0, err
+		}
+		if from != offset {
+			return 0, 0, fmt.Errorf("download: 请求从%d开始续传, 服务端从%d开始返回", offset, from)
+		}
+		flag |= os.O_APPEND
+		total = size
+	case http.StatusOK:
+		// 服务端不支持Range请求，或者文件已经变化，只能从头下载
+		flag |= os.O_TRUNC
+		offset = 0
+		total = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, 0, errRangeNotSatisfiable
+	default:
+		return 0, 0, fmt.Errorf("download: %s返回%s", rawURL, resp.Status)
+	}
+
+	// 先保存validator再写数据，中断后.part文件中的数据总是和validator对应
+	if err := writeValidator(part, responseValidator(resp)); err != nil {
+		return 0, 0, err
+	}
+	f, err := os.OpenFile(part, flag, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	pw := &progressWriter{w: f, url: rawURL, downloaded: offset, total: total, report: d.OnProgress}
+	pw.emit()
+	n, err := io.Copy(pw, resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, 0, err
+	}
+	if total >= 0 && offset+n != total {
+		return 0, 0, fmt.Errorf("download: %s下载不完整, 期望%d字节, 实际%d字节", rawURL, total, offset+n)
+	}
+	return offset + n, offset, nil
+}
+
+func (d *Downloader) destPath(t Task) string {
+	if t.Path != "" {
+		return t.Path
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	// ".."或者包含路径分隔符等的名称会把文件保存到Dir之外
+	if name == "/" || name == "." || !filepath.IsLocal(name) {
+		return ""
+	}
+	return filepath.Join(d.Dir, name)
+}
+
+// responseValidator 返回响应中可以用于If-Range的ETag或Last-Modified，弱ETag不能用于If-Range
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// readValidator 读取part对应的validator，不存在时返回空字符串
+func readValidator(part string) string {
+	b, err := os.ReadFile(part + validatorSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// writeValidator 保存part对应的validator，validator为空时删除之前保存的
+func writeValidator(part, validator string) error {
+	name := part + validatorSuffix
+	if validator == "" {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(name, []byte(validator), 0644)
+}
+
+// removePart 删除.part文件和对应的validator
+func removePart(part string) error {
+	if err := os.Remove(part); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return writeValidator(part, "")
+}
+
+// partSize 返回已下载部分的大小，文件不存在时为0
+func partSize(part string) (int64, error) {
+	fi, err := os.Stat(part)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// parseContentRange 解析"bytes start-end/total"，total为*时返回-1
+func parseContentRange(s string) (start, total int64, err error) {
+	spec, ok := strings.CutPrefix(s, "bytes ")
+	rng, size, ok2 := strings.Cut(spec, "/")
+	first, _, ok3 := strings.Cut(rng, "-")
+	if !ok || !ok2 || !ok3 {
+		return 0, 0, fmt.Errorf("download: 无效的Content-Range %q", s)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("download: 无效的Content-Range %q", s)
+	}
+	if size == "*" {
+		return start, -1, nil
+	}
+	if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("download: 无效的Content-Range %q", s)
+	}
+	return start, total, nil
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// progressWriter 在每次写入后报告进度
+type progressWriter struct {
+	w          io.Writer
+	url        string
+	downloaded int64
+	total      int64
+	report     func(Progress)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.downloaded += int64(n)
+	pw.emit()
+	return n, err
+}
+
+func (pw *progressWriter) emit() {
+	if pw.report != nil {
+		pw.report(Progress{URL: pw.url, Downloaded: pw.downloaded, Total: pw.total})
+	}
+}
@@ -0,0 +1,276 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newServer 启动一个测试服务器，/<name>返回files[name]，支持Range请求，并记录收到的Range header
+func newServer(t *testing.T, files map[string][]byte) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	ranges := new(sync.Map)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		data, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			ranges.Store(name, rng)
+		}
+		if strings.HasPrefix(name, "slow") {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ranges
+}
+
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func TestDownload(t *testing.T) {
+	files := map[string][]byte{
+		"a.txt": bytes.Repeat([]byte("a"), 100000),
+		"b.txt": bytes.Repeat([]byte("b"), 200),
+		"c.txt": []byte("c"),
+	}
+	srv, _ := newServer(t, files)
+	dir := t.TempDir()
+	var mu sync.Mutex
+	last := make(map[string]Progress)
+	d := &Downloader{Dir: dir, Concurrency: 2, OnProgress: func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		last[p.URL] = p
+	}}
+	tasks := []Task{
+		{URL: srv.URL + "/a.txt", SHA256: sum(files["a.txt"])},
+		{URL: srv.URL + "/b.txt"},
+		{URL: srv.URL + "/c.txt", Path: filepath.Join(dir, "renamed.txt")},
+	}
+	results := d.Download(context.Background(), tasks)
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("results[%d]: %v", i, res.Err)
+		}
+		name := filepath.Base(tasks[i].URL)
+		got, err := os.ReadFile(res.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, files[name]) {
+			t.Errorf("%s内容不一致", name)
+		}
+		if p := last[tasks[i].URL]; p.Downloaded != int64(len(files[name])) || p.Total != int64(len(files[name])) {
+			t.Errorf("%s最后的进度为%+v", name, p)
+		}
+	}
+	if results[2].Path != filepath.Join(dir, "renamed.txt") {
+		t.Errorf("没有使用Task.Path: %s", results[2].Path)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	srv, ranges := newServer(t, map[string][]byte{"data.bin": data})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"+partSuffix), data[:4000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := &Downloader{Dir: dir}
+	res := d.Fetch(context.Background(), Task{URL: srv.URL + "/data.bin", SHA256: sum(data)})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if !res.Resumed {
+		t.Error("没有从断点续传")
+	}
+	if rng, _ := ranges.Load("data.bin"); rng != "bytes=4000-" {
+		t.Errorf("Range header为%v", rng)
+	}
+	if _, err := os.Stat(res.Path + partSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Error("下载完成后.part文件没有被重命名")
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	srv, _ := newServer(t, map[string][]byte{"x": []byte("hello")})
+	dir := t.TempDir()
+	d := &Downloader{Dir: dir}
+	res := d.Fetch(context.Background(), Task{URL: srv.URL + "/x", SHA256: sum([]byte("world"))})
+	if !errors.Is(res.Err, ErrChecksumMismatch) {
+		t.Fatalf("err=%v, 期望ErrChecksumMismatch", res.Err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("校验失败后目录中仍有文件: %v", entries)
+	}
+}
+
+func TestDownloadNotFound(t *testing.T) {
+	srv, _ := newServer(t, nil)
+	res := (&Downloader{Dir: t.TempDir()}).Fetch(context.Background(), Task{URL: srv.URL + "/missing"})
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "404") {
+		t.Fatalf("err=%v", res.Err)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	files := map[string][]byte{
+		"fast1": []byte("1"),
+		"slow":  []byte("2"),
+		"fast2": []byte("3"),
+	}
+	srv, _ := newServer(t, files)
+	dir := t.TempDir()
+	d := &Downloader{Dir: dir, Concurrency: 3}
+	start := time.Now()
+	done, err := d.First(context.Background(), []Task{
+		{URL: srv.URL + "/slow"},
+		{URL: srv.URL + "/fast1"},
+		{URL: srv.URL + "/fast2"},
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("First等待了慢速下载: %v", elapsed)
+	}
+	if len(done) != 2 {
+		t.Fatalf("返回了%d个结果", len(done))
+	}
+	for _, res := range done {
+		if strings.HasSuffix(res.Task.URL, "/slow") {
+			t.Errorf("慢速下载不应该在前2个中")
+		}
+	}
+}
+
+func TestFirstNotEnough(t *testing.T) {
+	srv, _ := newServer(t, map[string][]byte{"ok": []byte("1")})
+	d := &Downloader{Dir: t.TempDir()}
+	done, err := d.First(context.Background(), []Task{
+		{URL: srv.URL + "/ok"},
+		{URL: srv.URL + "/missing"},
+	}, 2)
+	if !errors.Is(err, ErrNotEnough) {
+		t.Fatalf("err=%v, 期望ErrNotEnough", err)
+	}
+	if len(done) != 1 {
+		t.Fatalf("返回了%d个结果", len(done))
+	}
+}
+
+func TestDownloadIfRange(t *testing.T) {
+	v1 := bytes.Repeat([]byte("1"), 10000)
+	v2 := bytes.Repeat([]byte("2"), 8000)
+	var mu sync.Mutex
+	data, etag, ifRange := v1, `"v1"`, ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ifRange = r.Header.Get("If-Range")
+		// ServeContent根据ETag判断If-Range是否匹配，不匹配时返回整个文件
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	part := filepath.Join(dir, "data.bin"+partSuffix)
+	d := &Downloader{Dir: dir}
+	task := Task{URL: srv.URL + "/data.bin"}
+
+	// 第一次下载中断，留下.part文件和服务端返回的ETag
+	os.WriteFile(part, v1[:4000], 0644)
+	os.WriteFile(part+validatorSuffix, []byte(`"v1"`), 0644)
+	res := d.Fetch(context.Background(), task)
+	if res.Err != nil || !res.Resumed {
+		t.Fatalf("err=%v, resumed=%v", res.Err, res.Resumed)
+	}
+	if ifRange != `"v1"` {
+		t.Errorf("If-Range header为%q", ifRange)
+	}
+	if _, err := os.Stat(part + validatorSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Error("下载完成后validator文件没有删除")
+	}
+
+	// 服务端的文件变了，If-Range不匹配，返回整个新文件
+	mu.Lock()
+	data, etag = v2, `"v2"`
+	mu.Unlock()
+	os.WriteFile(part, v1[:4000], 0644)
+	os.WriteFile(part+validatorSuffix, []byte(`"v1"`), 0644)
+	res = d.Fetch(context.Background(), task)
+	if res.Err != nil || res.Resumed {
+		t.Fatalf("err=%v, resumed=%v, 期望重新下载", res.Err, res.Resumed)
+	}
+	if got, _ := os.ReadFile(res.Path); !bytes.Equal(got, v2) {
+		t.Errorf("下载了%d字节, 期望新文件的%d字节", len(got), len(v2))
+	}
+}
+
+func TestDownloadNoValidator(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	srv, ranges := newServer(t, map[string][]byte{"data.bin": data})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "data.bin"+partSuffix), []byte("stale"), 0644)
+	// 没有validator也没有sha256，无法确认.part文件是否可用，从头下载
+	res := (&Downloader{Dir: dir}).Fetch(context.Background(), Task{URL: srv.URL + "/data.bin"})
+	if res.Err != nil || res.Resumed {
+		t.Fatalf("err=%v, resumed=%v", res.Err, res.Resumed)
+	}
+	if rng, ok := ranges.Load("data.bin"); ok {
+		t.Errorf("不应该发送Range header: %v", rng)
+	}
+	if got, _ := os.ReadFile(res.Path); !bytes.Equal(got, data) {
+		t.Errorf("文件内容不正确")
+	}
+}
+
+func TestDownloadUnsafeName(t *testing.T) {
+	d := &Downloader{Dir: t.TempDir()}
+	for _, u := range []string{"http://example.com/a/..", "http://example.com/%2e%2e", "http://example.com/"} {
+		res := d.Fetch(context.Background(), Task{URL: u})
+		if res.Err == nil || !strings.Contains(res.Err.Error(), "Task.Path") {
+			t.Errorf("%s: err=%v", u, res.Err)
+		}
+	}
+}
+
+func TestDownloadDuplicatePath(t *testing.T) {
+	srv, _ := newServer(t, map[string][]byte{"x/a.txt": []byte("x"), "y/a.txt": []byte("y")})
+	d := &Downloader{Dir: t.TempDir()}
+	tasks := []Task{{URL: srv.URL + "/x/a.txt"}, {URL: srv.URL + "/y/a.txt"}}
+	results := d.Download(context.Background(), tasks)
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if !errors.Is(results[1].Err, ErrDuplicatePath) {
+		t.Fatalf("err=%v, 期望ErrDuplicatePath", results[1].Err)
+	}
+	if got, _ := os.ReadFile(results[0].Path); string(got) != "x" {
+		t.Errorf("文件内容为%q", got)
+	}
+	if _, err := d.First(context.Background(), tasks, 1); !errors.Is(err, ErrDuplicatePath) {
+		t.Fatalf("First err=%v, 期望ErrDuplicatePath", err)
+	}
+}
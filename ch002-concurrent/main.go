@@ -1,9 +1,28 @@
 package main
 
-import "go-practice/ch002-concurrent/concurrent"
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"go-practice/ch002-concurrent/concurrent"
+	"go-practice/internal/randseed"
+)
 
 func main() {
-	concurrent.GoroutineDemo()
-	concurrent.SyncDemo()
-	concurrent.ContextDemo()
+	seedFlag := flag.String("seed", os.Getenv(randseed.Env), "随机数种子，为空时使用当前时间")
+	flag.Parse()
+	seed, generated, err := randseed.Parse(*seedFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if generated {
+		fmt.Fprintf(os.Stderr, "seed: %d\n", seed)
+	}
+
+	concurrent.GoroutineDemo(os.Stdout, rand.New(rand.NewSource(seed)), concurrent.RealClock)
+	concurrent.SyncDemo(os.Stdout, concurrent.RealClock)
+	concurrent.ContextDemo(os.Stdout, concurrent.RealClock)
 }
@@ -0,0 +1,133 @@
+/**
+ * tracing包在contextValueDemo的基础上，把"通过Context传递traceId"整理成可复用的工具
+ * 1. Context的key使用包内未导出的类型，不会和其他包的key冲突
+ * 2. 一次调用链共享一个trace ID，链路中的每个环节是一个span，span之间通过ParentID形成父子关系
+ * 3. 提供HTTP中间件，从请求header中读取trace ID(没有则自动生成)，并把它放入请求的Context中，
+ *    处理请求时启动的协程只要传递这个Context，就能拿到同一个trace ID
+ */
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header HTTP请求和响应中传递trace ID使用的header
+const Header = "X-Trace-Id"
+
+// maxTraceIDLen 接受的上游trace ID的最大长度
+const maxTraceIDLen = 128
+
+// ctxKey 未导出的key类型，其他包无法构造出相同的key，避免了使用字符串作为key时的冲突
+type ctxKey int
+
+const (
+	traceIDKey ctxKey = iota
+	spanKey
+)
+
+// Span 调用链中的一个环节
+type Span struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	ParentID string // 根span的ParentID为空
+}
+
+// NewTraceID 生成一个随机的trace ID(32位十六进制字符串)
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID 生成一个随机的span ID(16位十六进制字符串)
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read在支持的平台上不会返回错误
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTraceID 返回携带traceID的子Context
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceIDFrom 从Context中获取trace ID
+func TraceIDFrom(ctx context.Context) (string, bool) {
+	traceID, ok := ctx.Value(traceIDKey).(string)
+	return traceID, ok && traceID != ""
+}
+
+// SpanFrom 从Context中获取当前的span
+func SpanFrom(ctx context.Context) (*Span, bool) {
+	span, ok := ctx.Value(spanKey).(*Span)
+	return span, ok
+}
+
+/**
+ * StartSpan 在ctx下开启一个新的span
+ * 如果ctx中已经有span，新span作为它的子span；如果ctx中没有trace ID，会自动生成一个
+ * 返回的Context同时携带trace ID和新span，应该传递给后续的调用和协程
+ */
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	traceID, ok := TraceIDFrom(ctx)
+	if !ok {
+		traceID = NewTraceID()
+		ctx = WithTraceID(ctx, traceID)
+	}
+	span := &Span{Name: name, TraceID: traceID, SpanID: NewSpanID()}
+	if parent, ok := SpanFrom(ctx); ok {
+		span.ParentID = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// Inject 把ctx中的trace ID写入header，用于向下游发起请求
+func Inject(ctx context.Context, h http.Header) {
+	if traceID, ok := TraceIDFrom(ctx); ok {
+		h.Set(Header, traceID)
+	}
+}
+
+/**
+ * Extract 从header中读取上游传来的trace ID，没有时原样返回ctx
+ * trace ID会被写入日志和响应header，上游传来的值不合法(过长或包含不可见字符)时丢弃，生成一个新的
+ */
+func Extract(ctx context.Context, h http.Header) context.Context {
+	traceID := h.Get(Header)
+	if traceID == "" {
+		return ctx
+	}
+	if !validTraceID(traceID) {
+		traceID = NewTraceID()
+	}
+	return WithTraceID(ctx, traceID)
+}
+
+// validTraceID 只接受长度合适的可见ASCII字符，避免日志注入
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware 为每个请求开启一个span，并把trace ID写入响应header，方便客户端排查问题
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := StartSpan(Extract(r.Context(), r.Header), r.Method+" "+r.URL.Path)
+		w.Header().Set(Header, span.TraceID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
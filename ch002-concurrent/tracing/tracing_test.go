@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceID(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := TraceIDFrom(ctx); ok {
+		t.Fatal("空Context中不应该有trace ID")
+	}
+	if _, ok := TraceIDFrom(WithTraceID(ctx, "")); ok {
+		t.Fatal("空字符串不是有效的trace ID")
+	}
+	if got, ok := TraceIDFrom(WithTraceID(ctx, "abc")); !ok || got != "abc" {
+		t.Fatalf("TraceIDFrom = %q, %v", got, ok)
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	// ctx中没有trace ID时自动生成
+	ctx, root := StartSpan(context.Background(), "root")
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 || root.ParentID != "" {
+		t.Fatalf("root span: %+v", root)
+	}
+	if traceID, _ := TraceIDFrom(ctx); traceID != root.TraceID {
+		t.Fatalf("Context中的trace ID为%q, 期望%q", traceID, root.TraceID)
+	}
+
+	_, child := StartSpan(ctx, "child")
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || child.SpanID == root.SpanID {
+		t.Fatalf("child span: %+v, root span: %+v", child, root)
+	}
+
+	// 沿用ctx中已有的trace ID
+	_, span := StartSpan(WithTraceID(context.Background(), "upstream"), "server")
+	if span.TraceID != "upstream" || span.ParentID != "" {
+		t.Fatalf("span: %+v", span)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	h := http.Header{}
+	Inject(context.Background(), h)
+	if v := h.Get(Header); v != "" {
+		t.Fatalf("没有trace ID时不应写入header: %q", v)
+	}
+	ctx, span := StartSpan(context.Background(), "client")
+	Inject(ctx, h)
+	if got, _ := TraceIDFrom(Extract(context.Background(), h)); got != span.TraceID {
+		t.Fatalf("Extract = %q, 期望%q", got, span.TraceID)
+	}
+
+	if _, ok := TraceIDFrom(Extract(context.Background(), http.Header{})); ok {
+		t.Fatal("没有header时不应设置trace ID")
+	}
+	cases := []struct {
+		name, in string
+		keep     bool
+	}{
+		{"accept", "abc-123", true},
+		{"too long", strings.Repeat("a", maxTraceIDLen+1), false},
+		{"control chars", "abc\nforged log line", false},
+		{"non ascii", "追踪", false},
+	}
+	for _, c := range cases {
+		h := http.Header{}
+		h.Set(Header, c.in)
+		got, ok := TraceIDFrom(Extract(context.Background(), h))
+		if !ok || (got == c.in) != c.keep {
+			t.Errorf("%s: in %q, got %q", c.name, c.in, got)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got *Span
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = SpanFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(Header, "upstream")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got == nil || got.TraceID != "upstream" || got.Name != "GET /orders" {
+		t.Fatalf("span: %+v", got)
+	}
+	if v := rec.Header().Get(Header); v != "upstream" {
+		t.Fatalf("响应header中的trace ID为%q", v)
+	}
+
+	// 没有上游trace ID时生成新的，并返回给客户端
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if v := rec.Header().Get(Header); v == "" || v != got.TraceID {
+		t.Fatalf("响应header中的trace ID为%q, span的trace ID为%q", v, got.TraceID)
+	}
+}
@@ -1,8 +1,35 @@
 package main
 
-import "go-practice/ch003-web/web"
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-practice/ch003-web/web"
+)
 
 func main() {
-	web.CookieDemo()
-	// web.GetUrlContent("GET", "http://httpbin.org/ip", "")
+	// 配置的优先级：命令行参数 > 环境变量 > 默认值
+	cfg := web.DefaultServerConfig()
+	if err := cfg.LoadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	// 收到Ctrl+C(SIGINT)或kill(SIGTERM)时取消ctx，服务开始优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := web.CookieDemo(ctx, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// 命令行工具可以把Cookie保存到文件，下次运行时保持登录状态：
+	// _ = web.DefaultJar().Load("cookies.txt", web.JarNetscape)
+	// resp, err := web.GetUrlContent(ctx, "GET", "http://httpbin.org/ip", "")
+	// _ = web.DefaultJar().Save("cookies.txt", web.JarNetscape)
 }
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+var (
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+)
+
+// TokenBucket 令牌桶限流器
+type TokenBucket struct {
+	*store[bucket]
+	capacity float64
+	perSec   float64 // 每秒补充的令牌数
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // 上次补充令牌的时间
+}
+
+func NewTokenBucket(cfg Config) *TokenBucket {
+	cfg.setDefaults()
+	tb := &TokenBucket{
+		capacity: float64(cfg.Burst),
+		perSec:   float64(cfg.Rate) / cfg.Window.Seconds(),
+	}
+	// 桶已经补满的key和新key没有区别，可以删除
+	tb.store = newStore(cfg.CleanupInterval, func(b *bucket, now time.Time) bool {
+		return tb.refill(b, now) >= tb.capacity
+	})
+	return tb
+}
+
+func (tb *TokenBucket) Allow(key string) Decision {
+	return tb.with(key, func(now time.Time) *bucket {
+		return &bucket{tokens: tb.capacity, last: now}
+	}, func(b *bucket, now time.Time) Decision {
+		b.tokens = tb.refill(b, now)
+		b.last = now
+		d := Decision{Limit: int(tb.capacity)}
+		if b.tokens >= 1 {
+			b.tokens--
+			d.Allowed = true
+		} else {
+			d.RetryAfter = tb.duration(1 - b.tokens)
+		}
+		d.Remaining = int(math.Floor(b.tokens))
+		d.Reset = tb.duration(tb.capacity - b.tokens)
+		return d
+	})
+}
+
+// refill 返回补充到now时桶中的令牌数，不修改b
+func (tb *TokenBucket) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(tb.capacity, b.tokens+elapsed*tb.perSec)
+}
+
+// duration 补充n个令牌需要的时间
+func (tb *TokenBucket) duration(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(n / tb.perSec * float64(time.Second)))
+}
+
+/**
+ * SlidingWindow 滑动窗口限流器
+ * 记录每个key最近Window时间内每个请求的时间(滑动窗口日志)，计数是精确的，
+ * 每个key最多保存Rate个时间，Rate很大时占用的内存也会比较多
+ */
+type SlidingWindow struct {
+	*store[window]
+	limit  int
+	window time.Duration
+}
+
+type window struct {
+	times []time.Time // 窗口内被允许的请求的时间，从早到晚
+}
+
+func NewSlidingWindow(cfg Config) *SlidingWindow {
+	cfg.setDefaults()
+	sw := &SlidingWindow{limit: cfg.Rate, window: cfg.Window}
+	sw.store = newStore(cfg.CleanupInterval, func(w *window, now time.Time) bool {
+		sw.expire(w, now)
+		return len(w.times) == 0
+	})
+	return sw
+}
+
+func (sw *SlidingWindow) Allow(key string) Decision {
+	return sw.with(key, func(now time.Time) *window {
+		return &window{times: make([]time.Time, 0, sw.limit)}
+	}, func(w *window, now time.Time) Decision {
+		sw.expire(w, now)
+		d := Decision{Limit: sw.limit}
+		if len(w.times) < sw.limit {
+			w.times = append(w.times, now)
+			d.Allowed = true
+		} else {
+			// 最早的请求移出窗口后才有新的配额
+			d.RetryAfter = w.times[0].Add(sw.window).Sub(now)
+		}
+		d.Remaining = sw.limit - len(w.times)
+		if n := len(w.times); n > 0 {
+			d.Reset = w.times[n-1].Add(sw.window).Sub(now)
+		}
+		return d
+	})
+}
+
+// expire 删除已经移出窗口的请求时间
+func (sw *SlidingWindow) expire(w *window, now time.Time) {
+	start := now.Add(-sw.window)
+	i := 0
+	for i < len(w.times) && !w.times[i].After(start) {
+		i++
+	}
+	if i > 0 {
+		w.times = append(w.times[:0], w.times[i:]...)
+	}
+}
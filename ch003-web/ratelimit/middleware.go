@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyFunc 从请求中取出限流的key，返回空字符串表示这个请求不限流
+type KeyFunc func(r *http.Request) string
+
+/**
+ * ByIP 按客户端IP限流
+ * trustProxy为true时取X-Forwarded-For中的第一个IP，只有服务部署在可信的反向代理之后才能打开，
+ * 否则客户端可以伪造X-Forwarded-For绕过限流
+ */
+func ByIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) string {
+		if trustProxy {
+			if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+				first, _, _ := strings.Cut(xff, ",")
+				if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+					return "ip:" + ip.String()
+				}
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return "ip:" + host
+	}
+}
+
+// ByCookie 按Cookie的值限流，例如会话ID，请求没有这个Cookie时返回空字符串
+func ByCookie(name string) KeyFunc {
+	return func(r *http.Request) string {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return ""
+		}
+		return "cookie:" + name + ":" + c.Value
+	}
+}
+
+// ByHeader 按Header的值限流，例如API Key，请求没有这个Header时返回空字符串
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return "header:" + name + ":" + v
+		}
+		return ""
+	}
+}
+
+// FirstOf 依次尝试多个KeyFunc，返回第一个非空的key，例如FirstOf(ByCookie("session_id"), ByIP(false))
+func FirstOf(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, fn := range fns {
+			if key := fn(r); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// Middleware 限流中间件，被限流的请求返回429
+func Middleware(l Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			d := l.Allow(k)
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+			if !d.Allowed {
+				// Retry-After的单位是秒，向上取整，至少1秒
+				retry := ceilSeconds(d.RetryAfter)
+				if retry < 1 {
+					retry = 1
+				}
+				h.Set("Retry-After", strconv.Itoa(retry))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,125 @@
+/**
+ * ratelimit包为web服务提供限流，防止单个客户端的请求过多拖垮服务
+ * 1. 两种算法：
+ *    TokenBucket(令牌桶)：桶中最多Burst个令牌，按Rate/Window的速度补充，每个请求消耗一个，允许短时间的突发
+ *    SlidingWindow(滑动窗口)：任意一个Window长度的时间段内最多Rate个请求，比固定窗口平滑，不会在窗口边界放过两倍的请求
+ * 2. 按key分别限流，key可以是客户端IP、Cookie中的会话ID或者某个Header(例如API Key)
+ * 3. Middleware在响应中设置X-RateLimit-Limit/Remaining/Reset，被限流时返回429和Retry-After
+ * 4. 每个key的状态保存在内存中，后台协程定期清理已经恢复到初始状态的key，不再使用时调用Close停止
+ */
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Decision 一次请求的限流结果
+type Decision struct {
+	Allowed    bool
+	Limit      int           // 配额上限
+	Remaining  int           // 本次请求之后剩余的配额
+	Reset      time.Duration // 配额完全恢复还需要的时间
+	RetryAfter time.Duration // 被拒绝时，至少要等待多久才会有新的配额
+}
+
+// Limiter 限流器
+type Limiter interface {
+	Allow(key string) Decision
+	Close()
+}
+
+// Config 限流配置：每个key在Window时间内最多Rate个请求
+type Config struct {
+	Rate   int
+	Window time.Duration
+	// Burst 令牌桶的容量，即允许的突发请求数，默认等于Rate；滑动窗口不使用
+	Burst int
+	// CleanupInterval 后台清理的间隔，默认1分钟
+	CleanupInterval time.Duration
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Rate <= 0 {
+		cfg.Rate = 1
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Second
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = cfg.Rate
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = time.Minute
+	}
+}
+
+// store 按key保存限流状态，idle返回true的状态会被后台协程删除
+type store[S any] struct {
+	mu      sync.Mutex
+	entries map[string]*S
+	idle    func(s *S, now time.Time) bool
+	now     func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newStore[S any](interval time.Duration, idle func(s *S, now time.Time) bool) *store[S] {
+	st := &store[S]{
+		entries: make(map[string]*S),
+		idle:    idle,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	go st.cleanupLoop(interval)
+	return st
+}
+
+// with 在锁内获取(不存在时用init创建)key的状态并调用fn
+func (st *store[S]) with(key string, init func(now time.Time) *S, fn func(s *S, now time.Time) Decision) Decision {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	s, ok := st.entries[key]
+	if !ok {
+		s = init(now)
+		st.entries[key] = s
+	}
+	return fn(s, now)
+}
+
+// Len 返回当前保存的key的数量
+func (st *store[S]) Len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.entries)
+}
+
+func (st *store[S]) Close() {
+	st.stopOnce.Do(func() { close(st.stop) })
+}
+
+func (st *store[S]) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-ticker.C:
+			st.cleanup()
+		}
+	}
+}
+
+func (st *store[S]) cleanup() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	for k, s := range st.entries {
+		if st.idle(s, now) {
+			delete(st.entries, k)
+		}
+	}
+}
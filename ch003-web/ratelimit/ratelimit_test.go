@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeNow 返回一个可以手动推进的时钟
+func fakeNow() (now func() time.Time, advance func(time.Duration)) {
+	t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time { return t }, func(d time.Duration) { t = t.Add(d) }
+}
+
+func TestTokenBucket(t *testing.T) {
+	tb := NewTokenBucket(Config{Rate: 2, Window: time.Second, Burst: 4})
+	defer tb.Close()
+	now, advance := fakeNow()
+	tb.store.now = now
+
+	// 突发4个
+	for i := 0; i < 4; i++ {
+		if d := tb.Allow("a"); !d.Allowed || d.Remaining != 3-i {
+			t.Fatalf("request %d: %+v", i, d)
+		}
+	}
+	d := tb.Allow("a")
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond || d.Reset != 2*time.Second {
+		t.Fatalf("over burst: %+v", d)
+	}
+	// 其他key不受影响
+	if d := tb.Allow("b"); !d.Allowed {
+		t.Fatalf("other key: %+v", d)
+	}
+	// 每秒补充2个
+	advance(time.Second)
+	for i := 0; i < 2; i++ {
+		if d := tb.Allow("a"); !d.Allowed {
+			t.Fatalf("after refill %d: %+v", i, d)
+		}
+	}
+	if d := tb.Allow("a"); d.Allowed {
+		t.Fatalf("refill exceeded rate: %+v", d)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	sw := NewSlidingWindow(Config{Rate: 3, Window: time.Minute})
+	defer sw.Close()
+	now, advance := fakeNow()
+	sw.store.now = now
+
+	for i := 0; i < 3; i++ {
+		if d := sw.Allow("a"); !d.Allowed {
+			t.Fatalf("request %d: %+v", i, d)
+		}
+		advance(10 * time.Second)
+	}
+	// t=30s，第一个请求在t=0，t=60s之后才移出窗口
+	d := sw.Allow("a")
+	if d.Allowed || d.Remaining != 0 || d.RetryAfter != 30*time.Second {
+		t.Fatalf("over limit: %+v", d)
+	}
+	// 固定窗口在边界处会放过两倍的请求，滑动窗口不会
+	advance(30 * time.Second)
+	if d := sw.Allow("a"); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("after first expired: %+v", d)
+	}
+	if d := sw.Allow("a"); d.Allowed || d.RetryAfter != 10*time.Second {
+		t.Fatalf("second slot not yet free: %+v", d)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	tb := NewTokenBucket(Config{Rate: 1, Window: time.Second, Burst: 2})
+	defer tb.Close()
+	sw := NewSlidingWindow(Config{Rate: 1, Window: time.Second})
+	defer sw.Close()
+	now, advance := fakeNow()
+	tb.store.now, sw.store.now = now, now
+
+	tb.Allow("a")
+	sw.Allow("a")
+	tb.cleanup()
+	sw.cleanup()
+	if tb.Len() != 1 || sw.Len() != 1 {
+		t.Fatalf("active keys removed: %d %d", tb.Len(), sw.Len())
+	}
+	advance(time.Second)
+	tb.cleanup()
+	sw.cleanup()
+	if tb.Len() != 0 || sw.Len() != 0 {
+		t.Fatalf("idle keys kept: %d %d", tb.Len(), sw.Len())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tb := NewTokenBucket(Config{Rate: 1, Window: 10 * time.Second})
+	defer tb.Close()
+	h := Middleware(tb, FirstOf(ByHeader("X-API-Key"), ByIP(false)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remote, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := do("10.0.0.1:1234", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("first: %d %v", rec.Code, rec.Header())
+	}
+	// 同一个IP换一个端口仍然是同一个key
+	rec = do("10.0.0.1:5678", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+		t.Fatalf("second: %d %v", rec.Code, rec.Header())
+	}
+	// 带API Key时按API Key限流
+	if rec = do("10.0.0.1:1234", "k1"); rec.Code != http.StatusOK {
+		t.Fatalf("api key: %d", rec.Code)
+	}
+}
+
+func TestByIPTrustProxy(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.168.0.1:80"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	if got := ByIP(false)(req); got != "ip:192.168.0.1" {
+		t.Errorf("untrusted: %s", got)
+	}
+	if got := ByIP(true)(req); got != "ip:203.0.113.7" {
+		t.Errorf("trusted: %s", got)
+	}
+	if got := ByCookie("sid")(req); got != "" {
+		t.Errorf("missing cookie: %q", got)
+	}
+}
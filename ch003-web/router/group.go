@@ -0,0 +1,61 @@
+package router
+
+import "net/http"
+
+// Group 路由分组，注册路由时加上分组的前缀，并用分组的中间件包装handler
+// 中间件在注册路由时生效，所以Use要在注册路由之前调用
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Use 为分组添加中间件，只对之后注册的路由生效
+func (g *Group) Use(mws ...Middleware) {
+	g.middlewares = append(g.middlewares, mws...)
+}
+
+// Group 创建子分组，前缀和中间件都会叠加在父分组之后
+func (g *Group) Group(prefix string, mws ...Middleware) *Group {
+	all := append(append([]Middleware(nil), g.middlewares...), mws...)
+	return &Group{router: g.router, prefix: g.prefix + trimSlash(prefix), middlewares: all}
+}
+
+// Handle 注册路由，pattern相对于分组的前缀，pattern为"/"时对应前缀本身加上/
+func (g *Group) Handle(method, pattern string, h http.Handler) *Route {
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		h = g.middlewares[i](h)
+	}
+	return g.router.Handle(method, g.prefix+pattern, h)
+}
+
+func (g *Group) HandleFunc(method, pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(method, pattern, fn)
+}
+
+func (g *Group) GET(pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(http.MethodGet, pattern, fn)
+}
+
+func (g *Group) POST(pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPost, pattern, fn)
+}
+
+func (g *Group) PUT(pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPut, pattern, fn)
+}
+
+func (g *Group) PATCH(pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(http.MethodPatch, pattern, fn)
+}
+
+func (g *Group) DELETE(pattern string, fn http.HandlerFunc) *Route {
+	return g.Handle(http.MethodDelete, pattern, fn)
+}
+
+func trimSlash(prefix string) string {
+	for len(prefix) > 0 && prefix[len(prefix)-1] == '/' {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
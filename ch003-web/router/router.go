@@ -0,0 +1,347 @@
+/**
+ * router包是一个简单的HTTP路由，用来替代只能按前缀匹配的http.DefaultServeMux
+ * 1. 按请求方法匹配路由，方法为空表示匹配所有方法；注册了GET的路由自动处理HEAD
+ * 2. 路径参数：/users/{id}匹配一段，/static/{path...}匹配剩余的所有段(只能放在最后)
+ *    参数通过r.PathValue("id")获取，和标准库ServeMux的写法一致
+ * 3. 同一个路径可以匹配多个路由时，字面量优先于参数，参数优先于通配
+ * 4. 路由分组：同一组的路由有相同的前缀和中间件，分组可以嵌套
+ * 5. 路径不存在返回404，路径存在但方法不匹配时返回405并在Allow Header中列出支持的方法
+ * 6. 路由可以命名，通过URL(name, key, value...)反向生成URL，修改路径时不用改所有拼接URL的地方
+ */
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Middleware 中间件，和web包中的中间件签名一致
+type Middleware func(http.Handler) http.Handler
+
+var ErrRouteNotFound = errors.New("router: 路由不存在")
+
+type Router struct {
+	// NotFound 路径不存在时调用，默认http.NotFound
+	NotFound http.Handler
+	// MethodNotAllowed 方法不匹配时调用，调用前已经设置好Allow Header，默认返回405
+	MethodNotAllowed http.Handler
+
+	routes      []*Route
+	named       map[string]*Route
+	middlewares []Middleware
+	once        sync.Once
+	handler     http.Handler // 包装了全局中间件的dispatch，第一次处理请求时生成
+}
+
+// Route 一条路由
+type Route struct {
+	router   *Router
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.Handler
+	name     string
+}
+
+type segmentKind int
+
+const (
+	literalSegment  segmentKind = iota // 字面量
+	paramSegment                       // {name}
+	wildcardSegment                    // {name...}
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // 字面量的值或参数名
+}
+
+func New() *Router {
+	return &Router{named: make(map[string]*Route)}
+}
+
+// Use 添加全局中间件，对所有请求(包括404、405)生效，必须在处理第一个请求之前调用
+func (rt *Router) Use(mws ...Middleware) {
+	rt.middlewares = append(rt.middlewares, mws...)
+}
+
+// Handle 注册路由，method为空表示匹配所有方法，重复注册相同的方法和路径会panic
+func (rt *Router) Handle(method, pattern string, h http.Handler) *Route {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	for _, exist := range rt.routes {
+		if exist.method == method && samePattern(exist.segments, segments) {
+			panic(fmt.Sprintf("router: 重复注册路由 %s %s", method, pattern))
+		}
+	}
+	r := &Route{router: rt, method: method, pattern: pattern, segments: segments, handler: h}
+	rt.routes = append(rt.routes, r)
+	return r
+}
+
+func (rt *Router) HandleFunc(method, pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(method, pattern, fn)
+}
+
+func (rt *Router) GET(pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(http.MethodGet, pattern, fn)
+}
+
+func (rt *Router) POST(pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(http.MethodPost, pattern, fn)
+}
+
+func (rt *Router) PUT(pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(http.MethodPut, pattern, fn)
+}
+
+func (rt *Router) PATCH(pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(http.MethodPatch, pattern, fn)
+}
+
+func (rt *Router) DELETE(pattern string, fn http.HandlerFunc) *Route {
+	return rt.Handle(http.MethodDelete, pattern, fn)
+}
+
+// Group 创建路由分组，分组中的路由路径都以prefix开头，并依次经过mws
+func (rt *Router) Group(prefix string, mws ...Middleware) *Group {
+	return &Group{router: rt, prefix: trimSlash(prefix), middlewares: mws}
+}
+
+// Name 为路由命名，用于URL反向生成，名称重复会panic
+func (r *Route) Name(name string) *Route {
+	if _, ok := r.router.named[name]; ok {
+		panic(fmt.Sprintf("router: 重复的路由名称 %s", name))
+	}
+	r.name = name
+	r.router.named[name] = r
+	return r
+}
+
+// Pattern 返回路由注册时的路径
+func (r *Route) Pattern() string {
+	return r.pattern
+}
+
+/**
+ * URL 根据路由名称生成路径，params依次为参数名和参数值，例如：
+ * rt.URL("user", "id", "42") 对于/users/{id}返回/users/42
+ * 参数值会被转义；通配参数的值中的/保留
+ */
+func (rt *Router) URL(name string, params ...string) (string, error) {
+	r, ok := rt.named[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("router: 路由%s的参数必须是成对的参数名和参数值", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+	var b strings.Builder
+	for _, seg := range r.segments {
+		b.WriteByte('/')
+		switch seg.kind {
+		case literalSegment:
+			b.WriteString(seg.value)
+		case paramSegment, wildcardSegment:
+			v, ok := values[seg.value]
+			if !ok {
+				return "", fmt.Errorf("router: 路由%s缺少参数%s", name, seg.value)
+			}
+			if seg.kind == paramSegment {
+				b.WriteString(url.PathEscape(v))
+				continue
+			}
+			parts := strings.Split(v, "/")
+			for i, p := range parts {
+				parts[i] = url.PathEscape(p)
+			}
+			b.WriteString(strings.Join(parts, "/"))
+		}
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rt.once.Do(func() {
+		var h http.Handler = http.HandlerFunc(rt.dispatch)
+		for i := len(rt.middlewares) - 1; i >= 0; i-- {
+			h = rt.middlewares[i](h)
+		}
+		rt.handler = h
+	})
+	rt.handler.ServeHTTP(w, req)
+}
+
+// dispatch 找到最匹配的路由并处理请求
+func (rt *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	path := splitPath(req.URL.EscapedPath())
+	var best *Route
+	var bestParams map[string]string
+	allowed := make(map[string]bool)
+	for _, r := range rt.routes {
+		params, ok := r.match(path)
+		if !ok {
+			continue
+		}
+		if !r.allows(req.Method) {
+			if r.method != "" {
+				allowed[r.method] = true
+				if r.method == http.MethodGet {
+					allowed[http.MethodHead] = true
+				}
+			}
+			continue
+		}
+		if best == nil || moreSpecific(r, best) {
+			best, bestParams = r, params
+		}
+	}
+	if best != nil {
+		for k, v := range bestParams {
+			req.SetPathValue(k, v)
+		}
+		best.handler.ServeHTTP(w, req)
+		return
+	}
+	if len(allowed) > 0 {
+		methods := make([]string, 0, len(allowed))
+		for m := range allowed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		if rt.MethodNotAllowed != nil {
+			rt.MethodNotAllowed.ServeHTTP(w, req)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if rt.NotFound != nil {
+		rt.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (r *Route) allows(method string) bool {
+	return r.method == "" || r.method == method || (r.method == http.MethodGet && method == http.MethodHead)
+}
+
+// match 匹配已经按/拆分、仍处于转义状态的路径，返回解码后的参数
+func (r *Route) match(path []string) (map[string]string, bool) {
+	var params map[string]string
+	for i, seg := range r.segments {
+		if seg.kind == wildcardSegment {
+			rest, err := url.PathUnescape(strings.Join(path[i:], "/"))
+			if err != nil {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[seg.value] = rest
+			return params, true
+		}
+		if i >= len(path) {
+			return nil, false
+		}
+		switch seg.kind {
+		case literalSegment:
+			if v, err := url.PathUnescape(path[i]); err != nil || v != seg.value {
+				return nil, false
+			}
+		case paramSegment:
+			v, err := url.PathUnescape(path[i])
+			if err != nil || v == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[seg.value] = v
+		}
+	}
+	return params, len(path) == len(r.segments)
+}
+
+// moreSpecific 从前往后逐段比较，字面量优先于参数，参数优先于通配；都相同时指定了方法的优先
+func moreSpecific(a, b *Route) bool {
+	for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+		if a.segments[i].kind != b.segments[i].kind {
+			return a.segments[i].kind < b.segments[i].kind
+		}
+	}
+	if len(a.segments) != len(b.segments) {
+		return len(a.segments) > len(b.segments)
+	}
+	return a.method != "" && b.method == ""
+}
+
+func samePattern(a, b []segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].kind != b[i].kind || (a[i].kind == literalSegment && a[i].value != b[i].value) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPath 把路径按/拆分，"/"拆分为空，"/users/"的最后一段是空字符串
+func splitPath(p string) []string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("router: 路径必须以/开头: %q", pattern)
+	}
+	parts := splitPath(pattern)
+	segments := make([]segment, 0, len(parts))
+	names := make(map[string]bool)
+	for i, p := range parts {
+		if !strings.HasPrefix(p, "{") || !strings.HasSuffix(p, "}") {
+			if strings.ContainsAny(p, "{}") {
+				return nil, fmt.Errorf("router: 参数必须占据完整的一段: %q", pattern)
+			}
+			segments = append(segments, segment{kind: literalSegment, value: p})
+			continue
+		}
+		name := p[1 : len(p)-1]
+		kind := paramSegment
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("router: 通配参数只能放在最后: %q", pattern)
+			}
+			name = strings.TrimSuffix(name, "...")
+			kind = wildcardSegment
+		}
+		if name == "" || names[name] {
+			return nil, fmt.Errorf("router: 参数名为空或重复: %q", pattern)
+		}
+		names[name] = true
+		segments = append(segments, segment{kind: kind, value: name})
+	}
+	return segments, nil
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echo 返回路由名和参数，例如"user id=42"
+func echo(name string, params ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := []string{name}
+		for _, p := range params {
+			out = append(out, p+"="+r.PathValue(p))
+		}
+		w.Write([]byte(strings.Join(out, " ")))
+	}
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestRouterMatch(t *testing.T) {
+	rt := New()
+	rt.GET("/", echo("root"))
+	rt.GET("/users", echo("users"))
+	rt.GET("/users/new", echo("new-user"))
+	rt.GET("/users/{id}", echo("user", "id"))
+	rt.DELETE("/users/{id}", echo("delete-user", "id"))
+	rt.GET("/users/{id}/posts/{post}", echo("post", "id", "post"))
+	rt.GET("/static/{path...}", echo("static", "path"))
+	rt.HandleFunc("", "/any", echo("any"))
+
+	cases := []struct {
+		method, target string
+		status         int
+		body           string
+	}{
+		{"GET", "/", 200, "root"},
+		{"GET", "/users", 200, "users"},
+		{"GET", "/users/new", 200, "new-user"},
+		{"GET", "/users/42", 200, "user id=42"},
+		{"GET", "/users/a%2Fb", 200, "user id=a/b"},
+		{"HEAD", "/users/42", 200, "user id=42"}, // 真实的服务器会丢弃HEAD响应的body
+		{"DELETE", "/users/42", 200, "delete-user id=42"},
+		{"GET", "/users/42/posts/7", 200, "post id=42 post=7"},
+		{"GET", "/static/css/site.css", 200, "static path=css/site.css"},
+		{"GET", "/static/", 200, "static path="},
+		{"PATCH", "/any", 200, "any"},
+		{"GET", "/users/42/", 404, ""},
+		{"GET", "/nothing", 404, ""},
+	}
+	for _, c := range cases {
+		rec := serve(rt, c.method, c.target)
+		if rec.Code != c.status {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.target, rec.Code, c.status)
+			continue
+		}
+		if c.status == 200 && rec.Body.String() != c.body {
+			t.Errorf("%s %s: body %q, want %q", c.method, c.target, rec.Body.String(), c.body)
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	rt := New()
+	rt.GET("/users/{id}", echo("user"))
+	rt.DELETE("/users/{id}", echo("delete"))
+	rec := serve(rt, http.MethodPost, "/users/1")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "DELETE, GET, HEAD" {
+		t.Fatalf("Allow: %q", got)
+	}
+
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	if rec := serve(rt, http.MethodGet, "/missing"); rec.Code != http.StatusTeapot {
+		t.Fatalf("custom NotFound: status %d", rec.Code)
+	}
+}
+
+func TestRouterGroupMiddleware(t *testing.T) {
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(name + ">"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	rt := New()
+	rt.Use(tag("global"))
+	api := rt.Group("/api/", tag("api"))
+	v1 := api.Group("/v1", tag("v1"))
+	v1.GET("/users/{id}", echo("user", "id"))
+	api.GET("/ping", echo("ping"))
+
+	if got := serve(rt, "GET", "/api/v1/users/1").Body.String(); got != "global>api>v1>user id=1" {
+		t.Fatalf("nested group: %q", got)
+	}
+	if got := serve(rt, "GET", "/api/ping").Body.String(); got != "global>api>ping" {
+		t.Fatalf("group: %q", got)
+	}
+	// 全局中间件对404也生效
+	if got := serve(rt, "GET", "/nothing").Body.String(); !strings.HasPrefix(got, "global>") {
+		t.Fatalf("404: %q", got)
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	rt := New()
+	rt.GET("/users/{id}", echo("user")).Name("user")
+	rt.GET("/static/{path...}", echo("static")).Name("static")
+	rt.GET("/", echo("root")).Name("root")
+
+	cases := []struct {
+		name   string
+		params []string
+		want   string
+	}{
+		{"user", []string{"id", "42"}, "/users/42"},
+		{"user", []string{"id", "a b/c"}, "/users/a%20b%2Fc"},
+		{"static", []string{"path", "css/site.css"}, "/static/css/site.css"},
+		{"root", nil, "/"},
+	}
+	for _, c := range cases {
+		got, err := rt.URL(c.name, c.params...)
+		if err != nil || got != c.want {
+			t.Errorf("URL(%s, %v) = %q, %v; want %q", c.name, c.params, got, err, c.want)
+		}
+	}
+	if _, err := rt.URL("missing"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("URL(missing): %v", err)
+	}
+	if _, err := rt.URL("user"); err == nil {
+		t.Error("URL(user) without id succeeded")
+	}
+	// 生成的URL可以匹配回原来的路由
+	u, _ := rt.URL("user", "id", "a b/c")
+	if got := serve(rt, "GET", u).Body.String(); got != "user" {
+		t.Errorf("round trip %s: %q", u, got)
+	}
+}
+
+func TestRouterInvalidPattern(t *testing.T) {
+	for _, p := range []string{"users", "/users/{id", "/a/{p...}/b", "/a/{id}/{id}", "/a/x{id}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("pattern %q accepted", p)
+				}
+			}()
+			New().GET(p, echo("x"))
+		}()
+	}
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * Client 对http.Client的封装
+ * 1. 整个请求(包括读取body)有超时时间，也可以通过ctx取消
+ * 2. 幂等方法(GET、HEAD、OPTIONS、TRACE、PUT、DELETE)遇到网络错误、5xx或429时自动重试，
+ *    重试间隔按指数退避增长并加上随机抖动，避免大量客户端在同一时刻重试；响应带有Retry-After时按它等待
+ * 3. 默认带有CookieJar，服务端设置的Cookie会在之后的请求中自动带上
+ * 4. 每个请求都带上默认Header，BeforeRequest/AfterResponse可以在每次发送前后做统一处理，例如签名、记录日志
+ * 5. 读取完整的body后返回给调用方，不再直接打印
+ */
+
+// Response 响应，Body已经读取完毕
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ClientConfig Client的配置，零值字段使用默认值
+type ClientConfig struct {
+	Timeout     time.Duration     // 单次请求的超时时间，默认10秒
+	MaxRetries  int               // 最多重试次数，默认0即不重试
+	MinBackoff  time.Duration     // 第一次重试前的等待时间，默认100毫秒
+	MaxBackoff  time.Duration     // 重试等待时间的上限，默认5秒
+	MaxBodySize int64             // 响应body的最大字节数，默认10MB
+	Jar         http.CookieJar    // 默认使用cookiejar.New创建
+	Transport   http.RoundTripper // 默认http.DefaultTransport
+	Header      http.Header       // 每个请求默认带上的Header，请求中已有的Header不会被覆盖
+	// BeforeRequest 每次发送(包括重试)之前调用，返回error时放弃请求
+	BeforeRequest func(req *http.Request) error
+	// AfterResponse 每次发送之后调用，resp和err有一个为nil
+	AfterResponse func(req *http.Request, resp *Response, err error)
+}
+
+type Client struct {
+	cfg ClientConfig
+	hc  *http.Client
+
+	mu  sync.Mutex // 保护rnd
+	rnd *rand.Rand
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 5 * time.Second
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 10 << 20
+	}
+	if cfg.Jar == nil {
+		// cookiejar.New只有在Options不合法时才返回错误
+		cfg.Jar, _ = cookiejar.New(nil)
+	}
+	return &Client{
+		cfg: cfg,
+		hc:  &http.Client{Jar: cfg.Jar, Transport: cfg.Transport},
+		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Jar 返回Client使用的CookieJar
+func (c *Client) Jar() http.CookieJar {
+	return c.cfg.Jar
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
+	return c.Do(ctx, http.MethodGet, url, nil, nil)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body []byte) (*Response, error) {
+	return c.Do(ctx, http.MethodPost, url, body, http.Header{"Content-Type": {contentType}})
+}
+
+// Do 发送请求，body为nil表示没有body。只有请求没有发出去或者读取响应失败时才返回error，
+// 4xx、5xx等状态码通过Response.StatusCode返回，由调用方判断
+func (c *Client) Do(ctx context.Context, method, url string, body []byte, header http.Header) (*Response, error) {
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.cfg.MaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, retry, err := c.do(ctx, method, url, body, header)
+		if attempt >= retries || !retry || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := c.backoff(attempt, resp)
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// do 发送一次请求，retry表示失败的原因是否可以通过重试解决
+func (c *Client) do(ctx context.Context, method, url string, body []byte, header http.Header) (resp *Response, retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	var r io.Reader
+	if body != nil {
+		// 每次重试都需要一个新的Reader
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, vs := range header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	for k, vs := range c.cfg.Header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = append([]string(nil), vs...)
+		}
+	}
+	if c.cfg.BeforeRequest != nil {
+		if err := c.cfg.BeforeRequest(req); err != nil {
+			return nil, false, err
+		}
+	}
+	resp, retry, err = c.read(req)
+	if c.cfg.AfterResponse != nil {
+		c.cfg.AfterResponse(req, resp, err)
+	}
+	return resp, retry, err
+}
+
+func (c *Client) read(req *http.Request) (*Response, bool, error) {
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		// 连接失败、超时等网络错误
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	// 多读一个字节，用于判断body是否超过了限制
+	b, err := io.ReadAll(io.LimitReader(resp.Body, c.cfg.MaxBodySize+1))
+	if err != nil {
+		return nil, true, err
+	}
+	if int64(len(b)) > c.cfg.MaxBodySize {
+		return nil, false, fmt.Errorf("web: %s的响应body超过%d字节", req.URL, c.cfg.MaxBodySize)
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}, retry, nil
+}
+
+// backoff 第attempt次重试前的等待时间：MinBackoff*2^attempt，上限MaxBackoff，再随机取[d/2, d]
+func (c *Client) backoff(attempt int, resp *Response) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+			if d := time.Duration(s) * time.Second; d < c.cfg.MaxBackoff {
+				return d
+			}
+			return c.cfg.MaxBackoff
+		}
+	}
+	d := c.cfg.MinBackoff
+	for i := 0; i < attempt && d < c.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	c.mu.Lock()
+	jitter := time.Duration(c.rnd.Int63n(int64(d/2) + 1))
+	c.mu.Unlock()
+	return d/2 + jitter
+}
+
+// isIdempotent 幂等方法重复执行的效果和执行一次相同，可以安全地重试
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
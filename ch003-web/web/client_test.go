@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer 前failures次请求返回503，之后返回200和请求的body
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClientRetry(t *testing.T) {
+	srv, calls := flakyServer(t, 2)
+	c := NewClient(ClientConfig{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	resp, err := c.Do(context.Background(), http.MethodPut, srv.URL, []byte("body"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每次重试都要重新发送body
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "body" || *calls != 3 {
+		t.Fatalf("status=%d body=%q calls=%d", resp.StatusCode, resp.Body, *calls)
+	}
+}
+
+func TestClientNoRetryForPost(t *testing.T) {
+	srv, calls := flakyServer(t, 2)
+	c := NewClient(ClientConfig{MaxRetries: 3, MinBackoff: time.Millisecond})
+	resp, err := c.Post(context.Background(), srv.URL, "text/plain", []byte("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || *calls != 1 {
+		t.Fatalf("status=%d calls=%d", resp.StatusCode, *calls)
+	}
+}
+
+func TestClientRetriesExhausted(t *testing.T) {
+	srv, calls := flakyServer(t, 10)
+	c := NewClient(ClientConfig{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || *calls != 3 {
+		t.Fatalf("resp=%v err=%v calls=%d", resp, err, *calls)
+	}
+}
+
+func TestClientNetworkError(t *testing.T) {
+	srv, _ := flakyServer(t, 0)
+	srv.Close()
+	var attempts int
+	c := NewClient(ClientConfig{
+		MaxRetries:    1,
+		MinBackoff:    time.Millisecond,
+		AfterResponse: func(*http.Request, *Response, error) { attempts++ },
+	})
+	// 以前GetUrlContent在这里会因为resp为nil而panic
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err == nil || resp != nil || attempts != 2 {
+		t.Fatalf("resp=%v err=%v attempts=%d", resp, err, attempts)
+	}
+}
+
+func TestClientHeadersHooksAndCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/set" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc"})
+			return
+		}
+		c, _ := r.Cookie("sid")
+		w.Write([]byte(strings.Join([]string{r.Header.Get("User-Agent"), r.Header.Get("X-Sign"), c.String()}, "|")))
+	}))
+	defer srv.Close()
+	c := NewClient(ClientConfig{
+		Header: http.Header{"User-Agent": {"go-practice"}},
+		BeforeRequest: func(req *http.Request) error {
+			req.Header.Set("X-Sign", req.Method)
+			return nil
+		},
+	})
+	if _, err := c.Get(context.Background(), srv.URL+"/set"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(context.Background(), srv.URL+"/get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Body), "go-practice|GET|sid=abc"; got != want {
+		t.Fatalf("body=%q, want %q", got, want)
+	}
+}
+
+func TestClientMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+	c := NewClient(ClientConfig{MaxBodySize: 10, MaxRetries: 2})
+	if _, err := c.Get(context.Background(), srv.URL); err == nil {
+		t.Fatal("oversize body accepted")
+	}
+}
@@ -1,38 +1,142 @@
 package web
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
-	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"go-practice/ch001-basic/errs"
+	"go-practice/ch002-concurrent/tracing"
+	"go-practice/ch003-web/ratelimit"
+	"go-practice/ch003-web/router"
 )
 
-func CookieDemo() {
-	http.HandleFunc("/", testCookieHandler)
-	_ = http.ListenAndServe(":8085", nil)
+// CookieDemo 启动演示服务，阻塞直到ctx取消并完成优雅关闭，端口被占用等启动错误会直接返回
+func CookieDemo(ctx context.Context, cfg ServerConfig) error {
+	// 密钥每次启动随机生成，重启后之前签发的Cookie和会话全部失效
+	sc, err := NewSecureCookie(time.Hour, CookieKey{HashKey: randomKey(32), BlockKey: randomKey(32)})
+	if err != nil {
+		return err
+	}
+	store := NewMemoryStore(time.Minute)
+	defer store.Close()
+	sessions := NewSessionManager(store, randomKey(32), SessionConfig{IdleTimeout: 10 * time.Minute, AbsoluteTimeout: time.Hour})
+
+	// 每个IP每秒10个请求，允许20个的突发
+	limiter := ratelimit.NewTokenBucket(ratelimit.Config{Rate: 10, Window: time.Second, Burst: 20})
+	defer limiter.Close()
+
+	return NewServer(cfg, demoRouter(cfg, sc, sessions, limiter)).Run(ctx)
 }
 
-func testCookieHandler(w http.ResponseWriter, r *http.Request) {
-	// 从Request中获取cookie
-	c, err := r.Cookie("test_cookie")
-	fmt.Printf("cookie:%#v, err:%v\n", c, err)
-	// 设置cookie
-	cookie := &http.Cookie{
-		Name:   "test_cookie",
-		Value:  "Go-Web" + strconv.FormatInt(time.Now().UnixNano(), 10),
-		MaxAge: 3600,
-		Domain: "localhost",
-		Path:   "/",
+// demoRouter 注册CookieDemo的所有路由
+func demoRouter(cfg ServerConfig, sc *SecureCookie, sessions *SessionManager, limiter ratelimit.Limiter) *router.Router {
+	rt := router.New()
+	// 先确定request id，访问日志中才能带上它；Recover和限流放在访问日志之后，panic和被限流的请求也会记录
+	rt.Use(RequestID, AccessLog(nil), Recover, ratelimit.Middleware(limiter, ratelimit.ByIP(false)))
+	// tracing.Middleware为每个请求生成(或沿用上游传来的)trace ID，并放入请求的Context中
+	rt.Handle(http.MethodGet, "/", tracing.Middleware(testCookieHandler(sc)))
+	rt.GET("/demo/{name}", demoHandler)
+	if cfg.Debug {
+		// 查看请求带上的Cookie及其问题，例如testCookieHandler设置的Domain=localhost
+		// 调试接口可以读取和设置任意Cookie，只在显式开启时挂载；set和delete需要先GET /debug/cookies拿到CSRF token
+		mountDebugCookies(rt.Group("/debug/cookies", sessions.Middleware, CSRF(CSRFConfig{Mode: SynchronizerToken})))
 	}
+
 	/**
-	 * 应在具体数据返回之前设置Cookie，否则设置不成功
-	 * http.SetCookie
+	 * 会话演示
+	 * GET  /session/       访问次数加1并显示当前会话和CSRF token
+	 * POST /session/login  模拟登录：更换会话ID后记录用户名，例如 /session/login?user=tom
+	 * POST /session/logout 退出登录，销毁会话
+	 * login和logout需要在X-CSRF-Token Header或csrf_token表单字段中携带CSRF token
 	 */
-	http.SetCookie(w, cookie)
-	w.Write([]byte("hello world."))
+	g := rt.Group("/session", sessions.Middleware, CSRF(CSRFConfig{Mode: SynchronizerToken}))
+	g.GET("/", sessionShowHandler)
+	g.POST("/login", sessionLoginHandler(sessions))
+	g.POST("/logout", sessionLogoutHandler(sessions))
+	return rt
+}
+
+func sessionShowHandler(w http.ResponseWriter, r *http.Request) {
+	s := SessionFrom(r.Context())
+	visits, _ := strconv.Atoi(valueOr(s, "visits", "0"))
+	s.Set("visits", strconv.Itoa(visits+1))
+	user := valueOr(s, "user", "guest")
+	fmt.Fprintf(w, "user:%s, visits:%d, created:%s\ncsrf:%s\n", user, visits+1, s.CreatedAt().Format(time.RFC3339), CSRFToken(r.Context()))
+}
+
+func sessionLoginHandler(m *SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		if user == "" {
+			WriteError(w, r, errs.Public(errs.New(errs.InvalidArgument, "missing user"), "缺少参数user"))
+			return
+		}
+		// 登录后必须更换会话ID，否则攻击者可以把提前拿到的会话ID塞给受害者(会话固定攻击)
+		if err := m.Regenerate(w, r); err != nil {
+			WriteError(w, r, errs.Wrap(err, errs.Internal, "regenerate session"))
+			return
+		}
+		SessionFrom(r.Context()).Set("user", user)
+		// Regenerate已经作废了登录前的CSRF token，下一次GET /session/时会显示新的token
+		fmt.Fprintf(w, "user:%s logged in, csrf token已更换\n", user)
+	}
+}
+
+func sessionLogoutHandler(m *SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := m.Destroy(w, r); err != nil {
+			WriteError(w, r, errs.Wrap(err, errs.Internal, "destroy session"))
+			return
+		}
+		w.Write([]byte("bye."))
+	}
+}
+
+func valueOr(s *Session, key, def string) string {
+	if v, ok := s.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+func randomKey(n int) []byte {
+	key := make([]byte, n)
+	_, _ = rand.Read(key)
+	return key
+}
+
+// testCookieHandler test_cookie的值经过sc签名和加密，浏览器中看到的是密文，被修改后解码失败
+func testCookieHandler(sc *SecureCookie) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, _ := tracing.TraceIDFrom(r.Context())
+		// 从Request中获取cookie并解码
+		v, err := sc.Cookie(r, "test_cookie")
+		log.Printf("[%s] traceId:%s, test_cookie:%q, err:%v", RequestIDFrom(r.Context()), traceID, v, err)
+		// 设置cookie
+		cookie := &http.Cookie{
+			Name:   "test_cookie",
+			Value:  "Go-Web" + strconv.FormatInt(time.Now().UnixNano(), 10),
+			MaxAge: 3600,
+			Domain: "localhost",
+			Path:   "/",
+		}
+		/**
+		 * 应在具体数据返回之前设置Cookie，否则设置不成功
+		 * http.SetCookie，sc.SetCookie编码Value后调用http.SetCookie
+		 */
+		if err := sc.SetCookie(w, cookie); err != nil {
+			WriteError(w, r, errs.Wrap(err, errs.Internal, "set test_cookie"))
+			return
+		}
+		w.Write([]byte("hello world."))
+	})
 }
 
 func cookieDesc() {
@@ -47,35 +151,32 @@ func cookieDesc() {
 	// func (r *Request) AddCookie(c *Cookie)
 }
 
-func GetUrlContent(method, urlVal, data string) {
-	var client = &http.Client{}
-	var req *http.Request
+/**
+ * defaultClient GetUrlContent使用的Client
+ * CSRF token不再写死：服务端通过Cookie下发，CSRFTransport从Jar中读取后放到Header中
+ * x-request-id也不再写死：RequestIDTransport从ctx中取出request id放到Header中
+ */
+var (
+	defaultJar    = NewPersistentJar(nil)
+	defaultClient = NewClient(ClientConfig{MaxRetries: 2, Jar: defaultJar, Transport: &RequestIDTransport{Base: &CSRFTransport{Jar: defaultJar}}})
+)
+
+// DefaultJar 返回GetUrlContent使用的CookieJar，命令行工具可以在启动时Load、退出前Save，从而保持登录状态
+func DefaultJar() *PersistentJar {
+	return defaultJar
+}
+
+// GetUrlContent 发起请求并返回响应，data不为空时作为请求的body
+// 在处理请求时调用可以直接传入r.Context()，request id会传递给下游；其他情况可以用WithRequestID设置
+func GetUrlContent(ctx context.Context, method, urlVal, data string) (*Response, error) {
+	var body []byte
 	if data == "" {
 		urlArr := strings.Split(urlVal, "?")
 		if len(urlArr) == 2 {
 			urlVal = urlArr[0] + "?" + url.PathEscape(urlArr[1])
 		}
-		req, _ = http.NewRequest(method, urlVal, nil)
 	} else {
-		req, _ = http.NewRequest(method, urlVal, strings.NewReader(data))
-	}
-	// 添加cookie
-	cookie := &http.Cookie{
-		Name:  "x-csrf-token",
-		Value: "abc2def1gfl6kds1kfg0hnv3laf1kdl9sfl2s3fd6mnn0d",
-	}
-	req.AddCookie(cookie)
-
-	// 添加header
-	req.Header.Add("x-request-id", "f1gfl6kds1kfg0hn")
-
-	// 发起请求
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println(err)
+		body = []byte(data)
 	}
-	defer resp.Body.Close()
-	// 读取数据
-	b, _ := ioutil.ReadAll(resp.Body)
-	fmt.Println(string(b))
+	return defaultClient.Do(ctx, method, urlVal, body, nil)
 }
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-practice/ch001-basic/errs"
+)
+
+/**
+ * CSRF(跨站请求伪造)防护
+ * 浏览器向某个站点发请求时会自动带上该站点的Cookie，恶意页面可以借此以用户的身份提交表单
+ * 防护的思路是要求修改数据的请求额外携带一个恶意页面拿不到的token：
+ * 1. DoubleSubmit(双重提交Cookie)：token放在一个JS可读的Cookie中，请求时再把它复制到Header或表单字段，
+ *    两者一致才放行。恶意页面因为同源策略读不到这个Cookie，也就无法构造出正确的Header。
+ *    能够写入Cookie的攻击者(例如控制了子域名)可以自己设置一对相同的Cookie和Header，所以token带有服务端密钥的HMAC签名，
+ *    放在SessionManager.Middleware之后时签名还包含会话ID，别的会话的token也不能使用
+ * 2. SynchronizerToken(同步器token)：token保存在服务端的会话中，每个会话一个，请求携带的token必须和会话中的一致，
+ *    需要放在SessionManager.Middleware之后。为了方便客户端获取，同样会把token写入Cookie
+ * GET、HEAD、OPTIONS、TRACE是安全方法，不应修改数据，不做校验
+ * 客户端可以使用CSRFTransport自动从CookieJar中读取token并设置到Header
+ */
+
+// CSRFMode CSRF防护模式
+type CSRFMode int
+
+const (
+	DoubleSubmit CSRFMode = iota
+	SynchronizerToken
+)
+
+var (
+	ErrCSRFTokenMissing = errors.New("web: 缺少CSRF token")
+	ErrCSRFTokenInvalid = errors.New("web: CSRF token无效")
+)
+
+// CSRFConfig CSRF配置，零值字段使用默认值
+type CSRFConfig struct {
+	Mode       CSRFMode
+	CookieName string // 默认"csrf_token"
+	HeaderName string // 默认"X-CSRF-Token"
+	FormField  string // 默认"csrf_token"
+	Path       string // Cookie的Path，默认"/"
+	Secure     bool
+	// Key DoubleSubmit模式签名token的密钥，默认在调用CSRF时随机生成，重启后之前的token全部失效
+	Key []byte
+	// ErrorHandler 校验失败时调用，默认通过WriteError返回403
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func (cfg *CSRFConfig) setDefaults() {
+	if cfg.CookieName == "" {
+		cfg.CookieName = "csrf_token"
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = "X-CSRF-Token"
+	}
+	if cfg.FormField == "" {
+		cfg.FormField = "csrf_token"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.Key == nil {
+		cfg.Key = randomKey(32)
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			WriteError(w, r, errs.Public(errs.Wrap(err, errs.Forbidden, "csrf"), "CSRF token无效，请刷新页面后重试"))
+		}
+	}
+}
+
+// csrfSessionKey SynchronizerToken模式下token在会话中的key
+const csrfSessionKey = "csrf_token"
+
+type csrfCtxKey struct{}
+
+// CSRFToken 返回当前请求的CSRF token，用于渲染到页面的表单中
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfCtxKey{}).(string)
+	return token
+}
+
+// CSRF 返回CSRF防护中间件
+func CSRF(cfg CSRFConfig) func(http.Handler) http.Handler {
+	cfg.setDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := cfg.currentToken(w, r)
+			if err != nil {
+				WriteError(w, r, errs.Wrap(err, errs.Internal, "csrf"))
+				return
+			}
+			if !isSafeMethod(r.Method) {
+				if err := cfg.verify(r, token); err != nil {
+					cfg.ErrorHandler(w, r, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfCtxKey{}, token)))
+		})
+	}
+}
+
+// currentToken 返回当前有效的token，还没有时生成一个并写入Cookie(SynchronizerToken模式下同时保存到会话)
+func (cfg *CSRFConfig) currentToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	var token string
+	switch cfg.Mode {
+	case SynchronizerToken:
+		s := SessionFrom(r.Context())
+		if s == nil {
+			return "", errors.New("web: SynchronizerToken模式需要放在SessionManager.Middleware之后")
+		}
+		token, _ = s.Get(csrfSessionKey)
+		if token == "" {
+			token = newCSRFToken()
+			s.Set(csrfSessionKey, token)
+		}
+		if c, err := r.Cookie(cfg.CookieName); err == nil && c.Value == token {
+			return token, nil
+		}
+	default:
+		sid := csrfSessionID(r)
+		// 签名不正确(伪造的，或者会话已经更换)的token不能继续使用，重新签发
+		if c, err := r.Cookie(cfg.CookieName); err == nil && cfg.validSigned(c.Value, sid) {
+			return c.Value, nil
+		}
+		token = cfg.signToken(newCSRFToken(), sid)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:  cfg.CookieName,
+		Value: token,
+		Path:  cfg.Path,
+		// 客户端的JS需要读取这个Cookie，所以不能设置HttpOnly
+		HttpOnly: false,
+		Secure:   cfg.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+// verify 校验请求中携带的token，先取Header，没有时再取表单字段
+func (cfg *CSRFConfig) verify(r *http.Request, token string) error {
+	sent := r.Header.Get(cfg.HeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(cfg.FormField)
+	}
+	if sent == "" {
+		return ErrCSRFTokenMissing
+	}
+	if cfg.Mode == DoubleSubmit {
+		c, err := r.Cookie(cfg.CookieName)
+		// token是本次请求才生成的，说明请求没有携带Cookie
+		if err != nil || c.Value == "" {
+			return ErrCSRFTokenMissing
+		}
+		// 携带的Cookie签名不正确，currentToken已经重新签发了token
+		if c.Value != token {
+			return ErrCSRFTokenInvalid
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() string {
+	return base64.RawURLEncoding.EncodeToString(randomKey(32))
+}
+
+// csrfSessionID 返回当前会话的ID，没有使用SessionManager.Middleware时为空
+func csrfSessionID(r *http.Request) string {
+	if s := SessionFrom(r.Context()); s != nil {
+		return s.ID()
+	}
+	return ""
+}
+
+// signToken 返回"随机值.签名"形式的token，签名为HMAC(Key, 会话ID.随机值)
+func (cfg *CSRFConfig) signToken(nonce, sid string) string {
+	mac := hmac.New(sha256.New, cfg.Key)
+	mac.Write([]byte(sid + "." + nonce))
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validSigned token的签名是否由Key对当前会话签发
+func (cfg *CSRFConfig) validSigned(token, sid string) bool {
+	nonce, _, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	// hmac.Equal是常数时间比较，避免通过响应时间猜测签名
+	return hmac.Equal([]byte(token), []byte(cfg.signToken(nonce, sid)))
+}
+
+/**
+ * CSRFTransport 客户端使用的RoundTripper
+ * 发送非安全方法的请求时，从Jar中取出CSRF Cookie，把它的值设置到Header中，对应服务端的CSRF中间件
+ * 通常先发一个GET请求拿到Cookie，之后的POST等请求就会自动带上token
+ */
+type CSRFTransport struct {
+	Base       http.RoundTripper // 默认http.DefaultTransport
+	Jar        http.CookieJar    // 应和http.Client.Jar是同一个
+	CookieName string            // 默认"csrf_token"
+	HeaderName string            // 默认"X-CSRF-Token"
+}
+
+func (t *CSRFTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if isSafeMethod(req.Method) || t.Jar == nil {
+		return base.RoundTrip(req)
+	}
+	cookieName, headerName := t.CookieName, t.HeaderName
+	if cookieName == "" {
+		cookieName = "csrf_token"
+	}
+	if headerName == "" {
+		headerName = "X-CSRF-Token"
+	}
+	for _, c := range t.Jar.Cookies(req.URL) {
+		if c.Name == cookieName {
+			// RoundTripper不应修改传入的请求，复制一份再设置Header
+			req = req.Clone(req.Context())
+			req.Header.Set(headerName, c.Value)
+			break
+		}
+	}
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(CSRFToken(r.Context())))
+})
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	h := CSRF(CSRFConfig{})(okHandler)
+
+	// GET下发token
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != rec.Body.String() {
+		t.Fatalf("GET cookies=%v body=%q", cookies, rec.Body.String())
+	}
+	token := cookies[0]
+
+	cases := []struct {
+		name   string
+		cookie *http.Cookie
+		header string
+		form   string
+		want   int
+	}{
+		{"no token", token, "", "", http.StatusForbidden},
+		{"no cookie", nil, token.Value, "", http.StatusForbidden},
+		{"wrong header", token, "forged", "", http.StatusForbidden},
+		// 攻击者自己设置一对相同的Cookie和Header，没有服务端的签名
+		{"forged pair", &http.Cookie{Name: "csrf_token", Value: "forged.sig"}, "forged.sig", "", http.StatusForbidden},
+		{"header", token, token.Value, "", http.StatusOK},
+		{"form", token, "", token.Value, http.StatusOK},
+	}
+	for _, c := range cases {
+		var body *strings.Reader
+		if c.form != "" {
+			body = strings.NewReader(url.Values{"csrf_token": {c.form}}.Encode())
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.cookie != nil {
+			req.AddCookie(c.cookie)
+		}
+		if c.header != "" {
+			req.Header.Set("X-CSRF-Token", c.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, rec.Code, c.want)
+		}
+	}
+}
+
+func TestCSRFSynchronizerToken(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	sessions := NewSessionManager(store, []byte("key"), SessionConfig{})
+	h := sessions.Middleware(CSRF(CSRFConfig{Mode: SynchronizerToken})(okHandler))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Transport: &CSRFTransport{Jar: jar}}
+	post := func() int {
+		resp, err := client.Post(srv.URL, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// 还没有token时POST失败
+	if code := post(); code != http.StatusForbidden {
+		t.Fatalf("POST without token: %d", code)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// CSRFTransport从Jar中取出token放到Header中
+	if code := post(); code != http.StatusOK {
+		t.Fatalf("POST with token: %d", code)
+	}
+
+	// 同一个token换一个会话无效
+	u, _ := url.Parse(srv.URL)
+	var csrfCookie *http.Cookie
+	for _, c := range jar.Cookies(u) {
+		if c.Name == "csrf_token" {
+			csrfCookie = c
+		}
+	}
+	other, _ := cookiejar.New(nil)
+	other.SetCookies(u, []*http.Cookie{csrfCookie})
+	client = &http.Client{Jar: other, Transport: &CSRFTransport{Jar: other}}
+	if code := post(); code != http.StatusForbidden {
+		t.Fatalf("POST with token from another session: %d", code)
+	}
+}
+
+func TestCSRFErrorHandler(t *testing.T) {
+	var got error
+	h := CSRF(CSRFConfig{ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		w.WriteHeader(http.StatusTeapot)
+	}})(okHandler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	if rec.Code != http.StatusTeapot || !errors.Is(got, ErrCSRFTokenMissing) {
+		t.Fatalf("status %d, err %v", rec.Code, got)
+	}
+}
+
+func TestCSRFDoubleSubmitSession(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	sessions := NewSessionManager(store, []byte("key"), SessionConfig{})
+	h := sessions.Middleware(CSRF(CSRFConfig{Key: []byte("csrf key")})(okHandler))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	newClient := func() *http.Client {
+		jar, _ := cookiejar.New(nil)
+		return &http.Client{Jar: jar, Transport: &CSRFTransport{Jar: jar}}
+	}
+	post := func(client *http.Client) int {
+		resp, err := client.Post(srv.URL, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	client := newClient()
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if code := post(client); code != http.StatusOK {
+		t.Fatalf("POST with token: %d", code)
+	}
+
+	// token的签名包含会话ID，换一个会话无效
+	u, _ := url.Parse(srv.URL)
+	other := newClient()
+	resp, err = other.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for _, c := range client.Jar.Cookies(u) {
+		if c.Name == "csrf_token" {
+			other.Jar.SetCookies(u, []*http.Cookie{c})
+		}
+	}
+	if code := post(other); code != http.StatusForbidden {
+		t.Fatalf("POST with token from another session: %d", code)
+	}
+}
+
+func TestCSRFDefaultErrorHandler(t *testing.T) {
+	h := RequestID(CSRF(CSRFConfig{})(okHandler))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	var body ErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusForbidden || body.Code != "forbidden" || body.RequestID == "" || strings.Contains(body.Message, "web:") {
+		t.Fatalf("status %d, body %+v", rec.Code, body)
+	}
+}
@@ -0,0 +1,317 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-practice/ch001-basic/errs"
+	"go-practice/ch003-web/router"
+)
+
+/**
+ * Cookie调试接口，方便前端开发排查Cookie没有生效、没有被带上等问题
+ * GET  /debug/cookies          以JSON返回请求带上的所有Cookie及发现的问题
+ * POST /debug/cookies/inspect  请求body中每行一个Set-Cookie的值，返回解析出的属性及发现的问题
+ *                              参数host指定设置Cookie的主机(默认当前主机)，https=false表示通过HTTP设置
+ * POST /debug/cookies/set      按表单参数设置一个测试Cookie：name、value、path、domain、max_age、secure、http_only、same_site
+ * POST /debug/cookies/delete   按表单参数删除一个Cookie：name、path、domain(必须和设置时一致)
+ * 检查的问题包括：缺少Secure/HttpOnly/SameSite、超过4096字节、包含非法字符、Domain和当前主机不匹配、
+ * Domain为localhost或IP、__Secure-/__Host-前缀的要求等
+ */
+
+// CookieIssue 发现的问题，Level为error表示浏览器会拒绝或丢弃，warning表示存在安全隐患
+type CookieIssue struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// CookieReport 一个Cookie的解析结果
+type CookieReport struct {
+	Name        string        `json:"name"`
+	Value       string        `json:"value"`
+	Size        int           `json:"size"` // 名称+值的字节数
+	Path        string        `json:"path,omitempty"`
+	Domain      string        `json:"domain,omitempty"`
+	Expires     *time.Time    `json:"expires,omitempty"`
+	MaxAge      int           `json:"max_age,omitempty"`
+	Secure      bool          `json:"secure"`
+	HttpOnly    bool          `json:"http_only"`
+	SameSite    string        `json:"same_site,omitempty"`
+	Partitioned bool          `json:"partitioned,omitempty"`
+	Issues      []CookieIssue `json:"issues"`
+}
+
+func (rep *CookieReport) errorf(format string, args ...interface{}) {
+	rep.Issues = append(rep.Issues, CookieIssue{Level: "error", Message: fmt.Sprintf(format, args...)})
+}
+
+func (rep *CookieReport) warnf(format string, args ...interface{}) {
+	rep.Issues = append(rep.Issues, CookieIssue{Level: "warning", Message: fmt.Sprintf(format, args...)})
+}
+
+func mountDebugCookies(g *router.Group) {
+	g.GET("", debugCookiesHandler)
+	g.POST("/inspect", debugInspectHandler)
+	g.POST("/set", debugSetCookieHandler)
+	g.POST("/delete", debugDeleteCookieHandler)
+}
+
+// debugCookiesHandler 自己解析Cookie Header，r.Cookies()会悄悄丢弃格式不合法的Cookie，这里要把它们也报告出来
+func debugCookiesHandler(w http.ResponseWriter, r *http.Request) {
+	reports := []CookieReport{}
+	seen := make(map[string]int)
+	for _, line := range r.Header.Values("Cookie") {
+		for _, pair := range strings.Split(line, ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(pair, "=")
+			rep := CookieReport{Name: name, Value: value, Size: len(name) + len(value), Issues: []CookieIssue{}}
+			checkNameValue(&rep)
+			if len(rep.Issues) > 0 {
+				rep.warnf("Go的net/http会丢弃这个Cookie，r.Cookie(%q)取不到", name)
+			}
+			if seen[name]++; seen[name] == 2 {
+				rep.warnf("请求中有多个名为%s的Cookie，通常是Path或Domain不同的Cookie同时生效，服务端只会取到第一个", name)
+			}
+			reports = append(reports, rep)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"host": r.Host, "cookies": reports})
+}
+
+// debugInspectHandler 检查Set-Cookie，host参数指定设置Cookie的主机，默认为当前请求的主机
+func debugInspectHandler(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" {
+		host = r.Host
+	}
+	secure := r.URL.Query().Get("https") != "false"
+	reports := []CookieReport{}
+	sc := bufio.NewScanner(http.MaxBytesReader(w, r.Body, 1<<20))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		line = strings.TrimSpace(strings.TrimPrefix(line, "Set-Cookie:"))
+		if line == "" {
+			continue
+		}
+		c, err := http.ParseSetCookie(line)
+		if err != nil {
+			name, _, _ := strings.Cut(line, "=")
+			rep := CookieReport{Name: name, Issues: []CookieIssue{}}
+			rep.errorf("无法解析: %v", err)
+			reports = append(reports, rep)
+			continue
+		}
+		reports = append(reports, InspectCookie(c, host, secure))
+	}
+	if err := sc.Err(); err != nil {
+		WriteError(w, r, errs.Wrap(err, errs.InvalidArgument, "read Set-Cookie lines"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"host": host, "cookies": reports})
+}
+
+func debugSetCookieHandler(w http.ResponseWriter, r *http.Request) {
+	c := &http.Cookie{
+		Name:     r.FormValue("name"),
+		Value:    r.FormValue("value"),
+		Path:     r.FormValue("path"),
+		Domain:   r.FormValue("domain"),
+		Secure:   r.FormValue("secure") == "true",
+		HttpOnly: r.FormValue("http_only") == "true",
+	}
+	if c.Name == "" {
+		WriteError(w, r, errs.Public(errs.New(errs.InvalidArgument, "missing name"), "缺少参数name"))
+		return
+	}
+	if v := r.FormValue("max_age"); v != "" {
+		maxAge, err := strconv.Atoi(v)
+		if err != nil {
+			WriteError(w, r, errs.Public(errs.Wrap(err, errs.InvalidArgument, "invalid max_age"), "max_age必须是整数"))
+			return
+		}
+		c.MaxAge = maxAge
+	}
+	switch strings.ToLower(r.FormValue("same_site")) {
+	case "":
+	case "lax":
+		c.SameSite = http.SameSiteLaxMode
+	case "strict":
+		c.SameSite = http.SameSiteStrictMode
+	case "none":
+		c.SameSite = http.SameSiteNoneMode
+	default:
+		WriteError(w, r, errs.Public(errs.New(errs.InvalidArgument, "invalid same_site"), "same_site只能是lax、strict或none"))
+		return
+	}
+	rep := InspectCookie(c, r.Host, r.TLS != nil)
+	http.SetCookie(w, c)
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func debugDeleteCookieHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		WriteError(w, r, errs.Public(errs.New(errs.InvalidArgument, "missing name"), "缺少参数name"))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: name, Path: r.FormValue("path"), Domain: r.FormValue("domain"), MaxAge: -1})
+	writeJSON(w, http.StatusOK, map[string]string{"deleted": name})
+}
+
+// InspectCookie 检查一个将要通过Set-Cookie发送给host的Cookie，secure表示是否通过HTTPS发送
+func InspectCookie(c *http.Cookie, host string, secure bool) CookieReport {
+	rep := CookieReport{
+		Name:        c.Name,
+		Value:       c.Value,
+		Size:        len(c.Name) + len(c.Value),
+		Path:        c.Path,
+		Domain:      c.Domain,
+		MaxAge:      c.MaxAge,
+		Secure:      c.Secure,
+		HttpOnly:    c.HttpOnly,
+		SameSite:    sameSiteString(c.SameSite),
+		Partitioned: c.Partitioned,
+		Issues:      []CookieIssue{},
+	}
+	if !c.Expires.IsZero() {
+		expires := c.Expires
+		rep.Expires = &expires
+		if c.MaxAge == 0 && expires.Before(time.Now()) {
+			rep.warnf("Expires已经过去，浏览器会删除这个Cookie")
+		}
+	}
+	checkNameValue(&rep)
+
+	if !c.Secure {
+		rep.warnf("缺少Secure，Cookie会通过HTTP明文发送")
+	} else if !secure && !isLocalhost(hostOnly(host)) {
+		rep.errorf("设置了Secure，浏览器不接受通过HTTP设置的Secure Cookie")
+	}
+	if !c.HttpOnly {
+		rep.warnf("缺少HttpOnly，页面中的JS(包括XSS注入的脚本)可以读取")
+	}
+	switch c.SameSite {
+	case 0, http.SameSiteDefaultMode:
+		rep.warnf("缺少SameSite，不同浏览器的默认行为不一致，建议明确设置为Lax或Strict")
+	case http.SameSiteNoneMode:
+		if !c.Secure {
+			rep.errorf("SameSite=None必须同时设置Secure，否则浏览器会拒绝")
+		}
+	}
+	checkDomain(&rep, c.Domain, hostOnly(host))
+
+	if strings.HasPrefix(c.Name, "__Secure-") && !c.Secure {
+		rep.errorf("__Secure-前缀的Cookie必须设置Secure")
+	}
+	if strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || c.Domain != "" || c.Path != "/") {
+		rep.errorf("__Host-前缀的Cookie必须设置Secure、Path=/，并且不能设置Domain")
+	}
+	return rep
+}
+
+// checkNameValue 检查名称、值的字符和大小
+func checkNameValue(rep *CookieReport) {
+	if rep.Name == "" {
+		rep.errorf("名称为空")
+	}
+	for i := 0; i < len(rep.Name); i++ {
+		if !isTokenByte(rep.Name[i]) {
+			rep.errorf("名称中包含非法字符%q", rep.Name[i])
+			break
+		}
+	}
+	v := rep.Value
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	for i := 0; i < len(v); i++ {
+		if !isCookieValueByte(v[i]) {
+			rep.errorf("值中包含非法字符%q，空格、逗号、分号、反斜杠和非ASCII字符需要先编码(例如base64或URL编码)", v[i])
+			break
+		}
+	}
+	if rep.Size > MaxCookieSize {
+		rep.errorf("名称+值共%d字节，超过了浏览器的%d字节限制，浏览器会丢弃这个Cookie", rep.Size, MaxCookieSize)
+	}
+}
+
+// checkDomain 检查Domain属性和设置Cookie的主机是否匹配
+func checkDomain(rep *CookieReport, domain, host string) {
+	if domain == "" {
+		return
+	}
+	d := strings.ToLower(strings.TrimPrefix(domain, "."))
+	host = strings.ToLower(host)
+	if net.ParseIP(d) != nil {
+		rep.errorf("Domain不能是IP地址，应省略Domain")
+		return
+	}
+	if d == "localhost" {
+		rep.warnf("Domain=localhost会被部分浏览器拒绝，本地开发时应省略Domain")
+	}
+	if host != "" && host != d && !strings.HasSuffix(host, "."+d) {
+		rep.errorf("Domain %s和当前主机%s不匹配，浏览器会拒绝这个Cookie", domain, host)
+	}
+	if !strings.Contains(d, ".") && d != "localhost" {
+		rep.errorf("Domain %s是顶级域名，浏览器会拒绝", domain)
+	}
+}
+
+// isTokenByte RFC 7230中token允许的字符
+func isTokenByte(b byte) bool {
+	if b <= ' ' || b >= 0x7f {
+		return false
+	}
+	return !strings.ContainsRune(`()<>@,;:\"/[]?={}`, rune(b))
+}
+
+// isCookieValueByte RFC 6265中cookie-octet允许的字符
+func isCookieValueByte(b byte) bool {
+	return 0x20 < b && b < 0x7f && b != '"' && b != ',' && b != ';' && b != '\\'
+}
+
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostOnly 去掉端口号
+func hostOnly(hostport string) string {
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		return h
+	}
+	return hostport
+}
+
+func sameSiteString(s http.SameSite) string {
+	switch s {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
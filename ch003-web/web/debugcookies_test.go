@@ -0,0 +1,177 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-practice/ch003-web/ratelimit"
+	"go-practice/ch003-web/router"
+)
+
+// hasIssue 报告中是否有包含substr的问题
+func hasIssue(rep CookieReport, level, substr string) bool {
+	for _, is := range rep.Issues {
+		if is.Level == level && strings.Contains(is.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInspectCookie(t *testing.T) {
+	cases := []struct {
+		name      string
+		cookie    *http.Cookie
+		host      string
+		level     string
+		substr    string
+		wantClean bool
+	}{
+		{name: "clean", cookie: &http.Cookie{Name: "sid", Value: "abc", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode}, host: "example.com", wantClean: true},
+		{name: "no secure", cookie: &http.Cookie{Name: "a", Value: "b"}, host: "example.com", level: "warning", substr: "Secure"},
+		{name: "no httponly", cookie: &http.Cookie{Name: "a", Value: "b"}, host: "example.com", level: "warning", substr: "HttpOnly"},
+		{name: "no samesite", cookie: &http.Cookie{Name: "a", Value: "b"}, host: "example.com", level: "warning", substr: "SameSite"},
+		{name: "samesite none", cookie: &http.Cookie{Name: "a", Value: "b", SameSite: http.SameSiteNoneMode}, host: "example.com", level: "error", substr: "SameSite=None"},
+		{name: "oversize", cookie: &http.Cookie{Name: "a", Value: strings.Repeat("x", 4096)}, host: "example.com", level: "error", substr: "4096"},
+		{name: "invalid value", cookie: &http.Cookie{Name: "a", Value: "hello world"}, host: "example.com", level: "error", substr: "非法字符"},
+		{name: "invalid name", cookie: &http.Cookie{Name: "a b", Value: "c"}, host: "example.com", level: "error", substr: "名称中包含非法字符"},
+		{name: "localhost domain", cookie: &http.Cookie{Name: "a", Value: "b", Domain: "localhost"}, host: "localhost:8085", level: "warning", substr: "localhost"},
+		{name: "domain mismatch", cookie: &http.Cookie{Name: "a", Value: "b", Domain: "localhost"}, host: "127.0.0.1:8085", level: "error", substr: "不匹配"},
+		{name: "subdomain", cookie: &http.Cookie{Name: "a", Value: "b", Domain: ".example.com", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode}, host: "www.example.com", wantClean: true},
+		{name: "ip domain", cookie: &http.Cookie{Name: "a", Value: "b", Domain: "127.0.0.1"}, host: "127.0.0.1", level: "error", substr: "IP"},
+		{name: "host prefix", cookie: &http.Cookie{Name: "__Host-a", Value: "b", Secure: true, Path: "/app"}, host: "example.com", level: "error", substr: "__Host-"},
+	}
+	for _, c := range cases {
+		rep := InspectCookie(c.cookie, c.host, true)
+		if c.wantClean {
+			if len(rep.Issues) != 0 {
+				t.Errorf("%s: unexpected issues %v", c.name, rep.Issues)
+			}
+			continue
+		}
+		if !hasIssue(rep, c.level, c.substr) {
+			t.Errorf("%s: want %s containing %q, got %v", c.name, c.level, c.substr, rep.Issues)
+		}
+	}
+}
+
+func debugCookiesServer() http.Handler {
+	rt := router.New()
+	mountDebugCookies(rt.Group("/debug/cookies"))
+	return rt
+}
+
+func TestDebugCookiesHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/debug/cookies", nil)
+	req.Header.Set("Cookie", `sid=abc; bad=hello world; sid=other`)
+	rec := httptest.NewRecorder()
+	debugCookiesServer().ServeHTTP(rec, req)
+	var got struct {
+		Cookies []CookieReport `json:"cookies"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Cookies) != 3 {
+		t.Fatalf("cookies: %+v", got.Cookies)
+	}
+	if len(got.Cookies[0].Issues) != 0 {
+		t.Errorf("sid: %v", got.Cookies[0].Issues)
+	}
+	if !hasIssue(got.Cookies[1], "warning", "丢弃") {
+		t.Errorf("bad: %v", got.Cookies[1].Issues)
+	}
+	if !hasIssue(got.Cookies[2], "warning", "多个") {
+		t.Errorf("duplicate sid: %v", got.Cookies[2].Issues)
+	}
+}
+
+func TestDebugInspectHandler(t *testing.T) {
+	body := "Set-Cookie: test_cookie=v; Path=/; Domain=localhost; Max-Age=3600\nsid=abc; Secure; HttpOnly; SameSite=Lax\n"
+	req := httptest.NewRequest(http.MethodPost, "/debug/cookies/inspect?host=127.0.0.1:8085&https=false", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	debugCookiesServer().ServeHTTP(rec, req)
+	var got struct {
+		Cookies []CookieReport `json:"cookies"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Cookies) != 2 {
+		t.Fatalf("cookies: %+v", got.Cookies)
+	}
+	if c := got.Cookies[0]; c.MaxAge != 3600 || c.Domain != "localhost" || !hasIssue(c, "error", "不匹配") {
+		t.Errorf("test_cookie: %+v", c)
+	}
+	// 127.0.0.1是本机，通过HTTP设置Secure Cookie也可以
+	if c := got.Cookies[1]; !c.Secure || c.SameSite != "Lax" || len(c.Issues) != 0 {
+		t.Errorf("sid: %+v", c)
+	}
+}
+
+func TestDebugSetAndDeleteCookie(t *testing.T) {
+	h := debugCookiesServer()
+	req := httptest.NewRequest(http.MethodPost, "/debug/cookies/set?name=theme&value=dark&max_age=60&same_site=lax", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Value != "dark" || cookies[0].MaxAge != 60 {
+		t.Fatalf("set: status %d cookies %v", rec.Code, cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/debug/cookies/delete?name=theme", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	cookies = rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Fatalf("delete: cookies %v", cookies)
+	}
+}
+
+func TestDemoDebugRoutes(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	sessions := NewSessionManager(store, []byte("key"), SessionConfig{})
+	limiter := ratelimit.NewTokenBucket(ratelimit.Config{Rate: 100, Window: time.Second, Burst: 100})
+	defer limiter.Close()
+	sc, err := NewSecureCookie(time.Hour, CookieKey{HashKey: randomKey(32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有开启Debug时不挂载调试接口
+	rec := httptest.NewRecorder()
+	demoRouter(ServerConfig{}, sc, sessions, limiter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/cookies", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("debug disabled: status %d", rec.Code)
+	}
+
+	srv := httptest.NewServer(demoRouter(ServerConfig{Debug: true}, sc, sessions, limiter))
+	defer srv.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Transport: &CSRFTransport{Jar: jar}}
+	status := func(method, path string) int {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// 还没有CSRF token时不能设置Cookie
+	if code := status(http.MethodPost, "/debug/cookies/set?name=a&value=b"); code != http.StatusForbidden {
+		t.Fatalf("set without token: status %d", code)
+	}
+	if code := status(http.MethodGet, "/debug/cookies"); code != http.StatusOK {
+		t.Fatalf("GET: status %d", code)
+	}
+	if code := status(http.MethodPost, "/debug/cookies/set?name=a&value=b"); code != http.StatusOK {
+		t.Fatalf("set with token: status %d", code)
+	}
+}
@@ -0,0 +1,29 @@
+package web
+
+import (
+	"net/http"
+
+	"go-practice/ch001-basic/basic"
+	"go-practice/ch001-basic/errs"
+	"go-practice/internal/randseed"
+)
+
+/**
+ * 基础章节的演示都把输出写入io.Writer，http.ResponseWriter也实现了io.Writer
+ * 所以可以直接在浏览器中查看演示输出，例如访问 http://localhost:8085/demo/reflect
+ * 依赖随机数的演示可以通过seed参数复现，例如 http://localhost:8085/demo/if?seed=42
+ */
+func demoHandler(w http.ResponseWriter, r *http.Request) {
+	d, ok := basic.Lookup(r.PathValue("name"))
+	if !ok {
+		WriteError(w, r, errs.New(errs.NotFound, "demo not found").With("name", r.PathValue("name")))
+		return
+	}
+	seed, _, err := randseed.Parse(r.URL.Query().Get("seed"))
+	if err != nil {
+		WriteError(w, r, errs.Public(errs.Wrap(err, errs.InvalidArgument, "invalid seed"), "seed必须是整数"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	d.Exec(w, seed)
+}
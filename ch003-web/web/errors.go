@@ -0,0 +1,54 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-practice/ch001-basic/errs"
+	"go-practice/ch002-concurrent/concurrent"
+)
+
+/**
+ * handler中的错误统一通过WriteError返回
+ * 1. 状态码和提示信息来自errs包的注册表，内部错误信息(err.Error())不会返回给客户端
+ * 2. 5xx错误是服务端的问题，记录日志，带上request id、字段和调用栈(errs.WithStack或panic时的调用栈)，方便根据客户端报告的request id排查
+ */
+
+// ErrorBody 错误响应的JSON结构
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// WriteError 把err写为JSON错误响应
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errs.HTTPStatus(err)
+	id := RequestIDFrom(r.Context())
+	if status >= http.StatusInternalServerError {
+		logError(r, id, err)
+	}
+	writeJSON(w, status, ErrorBody{
+		Code:      errs.CodeOf(err).Error(),
+		Message:   errs.UserMessage(err),
+		RequestID: id,
+	})
+}
+
+func logError(r *http.Request, id string, err error) {
+	var b strings.Builder
+	for _, f := range errs.FieldsOf(err) {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	log.Printf("[%s] %s %s error: %v%s", id, r.Method, r.URL.Path, err, b.String())
+	// panic的调用栈优先，它包含panic发生的位置
+	var pe *concurrent.PanicError
+	if errors.As(err, &pe) {
+		log.Printf("[%s] stack:\n%s", id, pe.Stack)
+	} else if stack := errs.StackOf(err); stack != "" {
+		log.Printf("[%s] stack:\n%s", id, stack)
+	}
+}
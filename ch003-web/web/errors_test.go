@@ -0,0 +1,75 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go-practice/ch001-basic/errs"
+)
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		body   ErrorBody
+		logged bool
+	}{
+		{"not found", errs.New(errs.NotFound, "demo x"), http.StatusNotFound, ErrorBody{Code: "not_found", Message: "资源不存在", RequestID: "rid"}, false},
+		{"public", errs.Public(errs.New(errs.InvalidArgument, "bad"), "缺少参数"), http.StatusBadRequest, ErrorBody{Code: "invalid_argument", Message: "缺少参数", RequestID: "rid"}, false},
+		{"internal", errs.Wrap(errors.New("disk full"), errs.Internal, "save").(*errs.Error).With("user", "tom").WithStack(), http.StatusInternalServerError, ErrorBody{Code: "internal", Message: "服务器内部错误", RequestID: "rid"}, true},
+		{"plain", errors.New("oops"), http.StatusInternalServerError, ErrorBody{Code: "unknown", Message: "未知错误", RequestID: "rid"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			req = req.WithContext(WithRequestID(req.Context(), "rid"))
+			rec := httptest.NewRecorder()
+			WriteError(rec, req, c.err)
+			if rec.Code != c.status {
+				t.Errorf("status = %d, want %d", rec.Code, c.status)
+			}
+			var body ErrorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body != c.body {
+				t.Errorf("body = %+v (%v), want %+v", body, err, c.body)
+			}
+			// 内部错误信息只能出现在日志中
+			if strings.Contains(rec.Body.String(), c.err.Error()) {
+				t.Errorf("body leaks internal error: %s", rec.Body.String())
+			}
+			if logged := buf.Len() > 0; logged != c.logged {
+				t.Errorf("logged = %v, want %v: %s", logged, c.logged, buf.String())
+			}
+		})
+	}
+	if !strings.Contains(buf.String(), "[rid]") {
+		t.Errorf("log missing request id: %s", buf.String())
+	}
+}
+
+func TestWriteErrorLogsFieldsAndStack(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := errs.New(errs.Internal, "boom").With("user", "tom").WithStack()
+	WriteError(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", nil), err)
+	out := buf.String()
+	for _, want := range []string{"POST /login error: boom user=tom", "stack:", "TestWriteErrorLogsFieldsAndStack"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+}
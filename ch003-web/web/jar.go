@@ -0,0 +1,426 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * PersistentJar 可以保存到文件的CookieJar
+ * 标准库的cookiejar.Jar只保存在内存中，程序退出后登录状态就丢了，PersistentJar可以保存为文件，下次启动时再加载
+ * 1. 实现http.CookieJar，可以直接用于http.Client和web.Client
+ * 2. 支持JSON和Netscape cookies.txt两种格式，cookies.txt可以和curl(-b/-c)、浏览器插件导出的文件互通
+ * 3. 按RFC 6265处理过期时间(Max-Age优先于Expires)、Domain和Path匹配、Secure
+ * 4. 不接受Domain为公共后缀(例如com、co.uk)的Cookie，否则任何网站都可以给整个后缀下的所有网站设置Cookie
+ *    公共后缀列表通过cookiejar.PublicSuffixList传入(例如golang.org/x/net/publicsuffix)，
+ *    为nil时只把最后一级域名当作公共后缀
+ * 没有过期时间的会话Cookie也会被保存，和curl的行为一致，这样登录状态才能在多次运行之间保持
+ */
+
+// JarFormat 文件格式
+type JarFormat int
+
+const (
+	JarJSON     JarFormat = iota
+	JarNetscape           // Netscape cookies.txt格式
+)
+
+type PersistentJar struct {
+	psl cookiejar.PublicSuffixList
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*jarEntry // key为domain;path;name
+	seq     uint64               // 创建顺序，相同路径长度时先创建的Cookie排在前面
+}
+
+// jarEntry 保存的Cookie，字段导出用于JSON序列化
+type jarEntry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"` // 不带前导.
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"host_only"` // 没有Domain属性，只发送给完全相同的主机
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"http_only"`
+	SameSite string    `json:"same_site,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"` // 零值表示会话Cookie
+	Seq      uint64    `json:"-"`
+}
+
+func (e *jarEntry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e *jarEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// NewPersistentJar 创建一个空的PersistentJar，psl为nil时只把最后一级域名当作公共后缀
+func NewPersistentJar(psl cookiejar.PublicSuffixList) *PersistentJar {
+	return &PersistentJar{psl: psl, now: time.Now, entries: make(map[string]*jarEntry)}
+}
+
+// SetCookies 保存u的响应中设置的Cookie
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host := canonicalHost(u.Host)
+	if host == "" {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	for _, c := range cookies {
+		e, ok := j.newEntry(c, host, u.Path, now)
+		if !ok {
+			continue
+		}
+		if e.expired(now) {
+			// Max-Age<=0或者Expires已经过去，表示删除Cookie
+			delete(j.entries, e.key())
+			continue
+		}
+		if old, ok := j.entries[e.key()]; ok {
+			e.Seq = old.Seq
+		} else {
+			j.seq++
+			e.Seq = j.seq
+		}
+		j.entries[e.key()] = e
+	}
+}
+
+// newEntry 按RFC 6265 5.3节把Set-Cookie转换为jarEntry，不合法的Cookie返回false
+func (j *PersistentJar) newEntry(c *http.Cookie, host, requestPath string, now time.Time) (*jarEntry, bool) {
+	if c.Name == "" {
+		return nil, false
+	}
+	e := &jarEntry{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: sameSiteString(c.SameSite),
+	}
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = defaultCookiePath(requestPath)
+	}
+	switch {
+	case c.MaxAge < 0:
+		e.Expires = time.Unix(1, 0)
+	case c.MaxAge > 0:
+		e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		e.Expires = c.Expires
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if domain == "" {
+		e.Domain, e.HostOnly = host, true
+		return e, true
+	}
+	if net.ParseIP(host) != nil {
+		// IP地址只能设置不带Domain的Cookie，Domain和IP相同时也当作不带Domain处理
+		if domain != host {
+			return nil, false
+		}
+		e.Domain, e.HostOnly = host, true
+		return e, true
+	}
+	if j.isPublicSuffix(domain) {
+		// Domain是公共后缀时，只有主机本身就是这个后缀才接受(例如主机就是github.io)，并且只发给这个主机
+		if domain != host {
+			return nil, false
+		}
+		e.Domain, e.HostOnly = host, true
+		return e, true
+	}
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return nil, false
+	}
+	e.Domain = domain
+	return e, true
+}
+
+func (j *PersistentJar) isPublicSuffix(domain string) bool {
+	if j.psl != nil {
+		return j.psl.PublicSuffix(domain) == domain
+	}
+	return !strings.Contains(domain, ".")
+}
+
+// Cookies 返回请求u时应该带上的Cookie，路径长的排在前面
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host := canonicalHost(u.Host)
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	var matched []*jarEntry
+	for k, e := range j.entries {
+		if e.expired(now) {
+			delete(j.entries, k)
+			continue
+		}
+		if e.Secure && u.Scheme != "https" {
+			continue
+		}
+		if !domainMatch(e, host) || !pathMatch(e.Path, path) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Seq < matched[b].Seq
+	})
+	cookies := make([]*http.Cookie, len(matched))
+	for i, e := range matched {
+		cookies[i] = &http.Cookie{Name: e.Name, Value: e.Value}
+	}
+	return cookies
+}
+
+// Len 返回保存的Cookie数量(包括已过期但还没有被清理的)
+func (j *PersistentJar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.entries)
+}
+
+// Save 保存到文件，先写临时文件再重命名，文件权限为0600，因为Cookie中通常有登录凭证
+func (j *PersistentJar) Save(name string, format JarFormat) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	if format == JarNetscape {
+		err = j.WriteNetscape(w)
+	} else {
+		err = j.WriteJSON(w)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Load 从文件加载并合并到当前的Cookie中，文件不存在时不做任何事
+func (j *PersistentJar) Load(name string, format JarFormat) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format == JarNetscape {
+		return j.ReadNetscape(f)
+	}
+	return j.ReadJSON(f)
+}
+
+// snapshot 按创建顺序返回所有未过期的Cookie
+func (j *PersistentJar) snapshot() []*jarEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	entries := make([]*jarEntry, 0, len(j.entries))
+	for _, e := range j.entries {
+		if !e.expired(now) {
+			cp := *e
+			entries = append(entries, &cp)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Seq < entries[b].Seq })
+	return entries
+}
+
+// add 加入读取到的Cookie，已过期的忽略
+func (j *PersistentJar) add(entries []*jarEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	for _, e := range entries {
+		if e.expired(now) || e.Name == "" || e.Domain == "" {
+			continue
+		}
+		if e.Path == "" {
+			e.Path = "/"
+		}
+		e.Domain = strings.ToLower(strings.TrimPrefix(e.Domain, "."))
+		j.seq++
+		e.Seq = j.seq
+		j.entries[e.key()] = e
+	}
+}
+
+func (j *PersistentJar) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.snapshot())
+}
+
+func (j *PersistentJar) ReadJSON(r io.Reader) error {
+	var entries []*jarEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("web: 解析cookie文件失败: %w", err)
+	}
+	j.add(entries)
+	return nil
+}
+
+// httpOnlyPrefix curl在cookies.txt中用这个前缀标记HttpOnly的Cookie
+const httpOnlyPrefix = "#HttpOnly_"
+
+/**
+ * WriteNetscape 写为Netscape cookies.txt格式，每行7个字段，以Tab分隔：
+ * domain  include_subdomains  path  secure  expires  name  value
+ * 会话Cookie的expires为0
+ */
+func (j *PersistentJar) WriteNetscape(w io.Writer) error {
+	if _, err := io.WriteString(w, "# Netscape HTTP Cookie File\n"); err != nil {
+		return err
+	}
+	for _, e := range j.snapshot() {
+		domain, sub := e.Domain, "FALSE"
+		if !e.HostOnly {
+			domain, sub = "."+e.Domain, "TRUE"
+		}
+		if e.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !e.Expires.IsZero() {
+			expires = e.Expires.Unix()
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, sub, e.Path, netscapeBool(e.Secure), expires, e.Name, e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *PersistentJar) ReadNetscape(r io.Reader) error {
+	var entries []*jarEntry
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// 值为空时部分工具会省略最后一个Tab
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return fmt.Errorf("web: cookies.txt第%d行应有7个字段，实际%d个", lineNo, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("web: cookies.txt第%d行的过期时间无效: %w", lineNo, err)
+		}
+		e := &jarEntry{
+			Domain:   fields[0],
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			e.Expires = time.Unix(expires, 0)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	j.add(entries)
+	return nil
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// canonicalHost 去掉端口和末尾的.，转为小写
+func canonicalHost(host string) string {
+	host = hostOnly(host)
+	host = strings.TrimSuffix(host, ".")
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+func domainMatch(e *jarEntry, host string) bool {
+	if e.HostOnly {
+		return host == e.Domain
+	}
+	return host == e.Domain || (strings.HasSuffix(host, "."+e.Domain) && net.ParseIP(host) == nil)
+}
+
+// pathMatch RFC 6265 5.1.4节的路径匹配
+func pathMatch(cookiePath, requestPath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath 没有Path属性时，默认为请求路径最后一个/之前的部分
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// cookieString 把Cookies的结果拼成"a=1; b=2"，方便比较
+func cookieString(cookies []*http.Cookie) string {
+	parts := make([]string, len(cookies))
+	for i, c := range cookies {
+		parts[i] = c.Name + "=" + c.Value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fakePSL 把co.uk当作公共后缀
+type fakePSL struct{}
+
+func (fakePSL) PublicSuffix(domain string) string {
+	if strings.HasSuffix(domain, ".co.uk") || domain == "co.uk" {
+		return "co.uk"
+	}
+	return domain[strings.LastIndex(domain, ".")+1:]
+}
+func (fakePSL) String() string { return "fake" }
+
+func TestPersistentJarMatching(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	j := NewPersistentJar(fakePSL{})
+	j.now = func() time.Time { return now }
+
+	j.SetCookies(mustURL(t, "https://www.example.com/app/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},                                     // Path默认为/app
+		{Name: "domain", Value: "2", Domain: "example.com", Path: "/"}, // 发给所有子域名
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "deep", Value: "4", Path: "/app/admin"},
+		{Name: "short", Value: "5", Path: "/", MaxAge: 60},
+		{Name: "other", Value: "x", Domain: "other.com"}, // Domain和主机不匹配
+		{Name: "tld", Value: "x", Domain: "com"},         // 公共后缀
+	})
+	j.SetCookies(mustURL(t, "http://shop.co.uk/"), []*http.Cookie{
+		{Name: "psl", Value: "x", Domain: "co.uk"},
+	})
+
+	cases := []struct{ url, want string }{
+		{"https://www.example.com/app/admin/users", "deep=4; host=1; domain=2; secure=3; short=5"},
+		{"https://www.example.com/application", "domain=2; secure=3; short=5"},
+		{"http://www.example.com/app", "host=1; domain=2; short=5"},
+		{"https://api.example.com/", "domain=2"},
+		{"https://example.com/", "domain=2"},
+		{"https://notexample.com/", ""},
+		{"http://other.co.uk/", ""},
+	}
+	for _, c := range cases {
+		if got := cookieString(j.Cookies(mustURL(t, c.url))); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.url, got, c.want)
+		}
+	}
+
+	// MaxAge过期
+	now = now.Add(2 * time.Minute)
+	if got := cookieString(j.Cookies(mustURL(t, "http://example.com/"))); got != "domain=2" {
+		t.Errorf("after MaxAge: %q", got)
+	}
+	// MaxAge<0删除
+	j.SetCookies(mustURL(t, "https://www.example.com/"), []*http.Cookie{{Name: "domain", Domain: "example.com", Path: "/", MaxAge: -1}})
+	if got := cookieString(j.Cookies(mustURL(t, "http://example.com/"))); got != "" {
+		t.Errorf("after delete: %q", got)
+	}
+}
+
+func TestPersistentJarDefaultPublicSuffix(t *testing.T) {
+	j := NewPersistentJar(nil)
+	j.SetCookies(mustURL(t, "http://localhost:8085/"), []*http.Cookie{{Name: "a", Value: "1", Domain: "localhost"}})
+	j.SetCookies(mustURL(t, "http://www.example.com/"), []*http.Cookie{{Name: "b", Value: "2", Domain: "com"}})
+	// 主机本身就是后缀时接受，但只发给这个主机
+	if got := cookieString(j.Cookies(mustURL(t, "http://localhost/"))); got != "a=1" {
+		t.Errorf("localhost: %q", got)
+	}
+	if got := cookieString(j.Cookies(mustURL(t, "http://www.example.com/"))); got != "" {
+		t.Errorf("tld cookie accepted: %q", got)
+	}
+}
+
+func TestPersistentJarSaveLoad(t *testing.T) {
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, format := range []JarFormat{JarJSON, JarNetscape} {
+		j := NewPersistentJar(nil)
+		j.SetCookies(mustURL(t, "https://www.example.com/"), []*http.Cookie{
+			{Name: "sid", Value: "abc", HttpOnly: true},
+			{Name: "theme", Value: "dark", Domain: "example.com", Path: "/", Expires: future, Secure: true},
+		})
+		name := filepath.Join(t.TempDir(), "cookies")
+		if err := j.Save(name, format); err != nil {
+			t.Fatal(err)
+		}
+		loaded := NewPersistentJar(nil)
+		if err := loaded.Load(name, format); err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range []string{"https://www.example.com/", "https://api.example.com/", "http://www.example.com/"} {
+			want := cookieString(j.Cookies(mustURL(t, u)))
+			if got := cookieString(loaded.Cookies(mustURL(t, u))); got != want {
+				t.Errorf("format %d, %s: got %q, want %q", format, u, got, want)
+			}
+		}
+	}
+}
+
+func TestPersistentJarReadNetscape(t *testing.T) {
+	// curl -c生成的文件
+	txt := "# Netscape HTTP Cookie File\n" +
+		"#HttpOnly_.example.com\tTRUE\t/\tFALSE\t0\tsid\tabc\n" +
+		"www.example.com\tFALSE\t/api\tTRUE\t4102444800\ttoken\txyz\n" +
+		"old.example.com\tFALSE\t/\tFALSE\t1\texpired\tx\n"
+	j := NewPersistentJar(nil)
+	if err := j.ReadNetscape(strings.NewReader(txt)); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookieString(j.Cookies(mustURL(t, "https://www.example.com/api/v1"))); got != "token=xyz; sid=abc" {
+		t.Errorf("got %q", got)
+	}
+	if j.Len() != 2 {
+		t.Errorf("expired cookie loaded, Len=%d", j.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := j.WriteNetscape(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "#HttpOnly_.example.com\tTRUE\t/\tFALSE\t0\tsid\tabc\n") {
+		t.Errorf("WriteNetscape:\n%s", buf.String())
+	}
+
+	if err := j.ReadNetscape(strings.NewReader("bad line\n")); err == nil {
+		t.Error("malformed line accepted")
+	}
+}
+
+// TestPersistentJarKeepsLogin 模拟命令行工具：第一次运行登录并保存Cookie，第二次运行加载后仍然是登录状态
+func TestPersistentJarKeepsLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "tom", Path: "/", HttpOnly: true})
+			return
+		}
+		c, err := r.Cookie("sid")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(c.Value))
+	}))
+	defer srv.Close()
+	name := filepath.Join(t.TempDir(), "cookies.txt")
+
+	first := NewPersistentJar(nil)
+	if _, err := NewClient(ClientConfig{Jar: first}).Get(context.Background(), srv.URL+"/login"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Save(name, JarNetscape); err != nil {
+		t.Fatal(err)
+	}
+
+	second := NewPersistentJar(nil)
+	if err := second.Load(name, JarNetscape); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := NewClient(ClientConfig{Jar: second}).Get(context.Background(), srv.URL+"/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "tom" {
+		t.Fatalf("status %d body %q", resp.StatusCode, resp.Body)
+	}
+}
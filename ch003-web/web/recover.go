@@ -0,0 +1,37 @@
+package web
+
+import (
+	"net/http"
+
+	"go-practice/ch001-basic/errs"
+	"go-practice/ch002-concurrent/concurrent"
+)
+
+/**
+ * Recover 中间件，把handler中的panic转换为500响应，是basic包中connDB的defer+recover在web服务中的通用版本
+ * 1. net/http虽然也会recover每个请求的panic，但只是断开连接，客户端收不到任何响应
+ * 2. panic的值和调用栈通过WriteError记录日志，带上request id，需要放在RequestID之后
+ * 3. handler已经写出了部分响应时无法再返回500，只记录日志并用http.ErrAbortHandler断开连接，
+ *    handler主动panic(http.ErrAbortHandler)时也原样抛出，由net/http处理
+ */
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			err := errs.Wrap(concurrent.NewPanicError(v), errs.Internal, "recovered")
+			if rec.wroteHeader {
+				logError(r, RequestIDFrom(r.Context()), err)
+				panic(http.ErrAbortHandler)
+			}
+			WriteError(w, r, err)
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+)
+
+/**
+ * Request ID 用于把一次请求在各个服务中的日志串起来
+ * 1. RequestID中间件：沿用上游传来的X-Request-ID，没有或者不合法时生成一个，放入Context并在响应中返回
+ * 2. AccessLog中间件：每个请求记录一行访问日志，带上request id
+ * 3. RequestIDTransport：客户端调用下游服务时，把Context中的request id放到请求的Header中
+ * 和tracing包的trace ID不同，request id只是一个不透明的字符串，不区分span，方便和网关、负载均衡等外部系统对接
+ */
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen 上游传来的request id的最大长度，防止通过超长的Header污染日志
+const maxRequestIDLen = 128
+
+type requestIDCtxKey struct{}
+
+// WithRequestID 返回带有request id的Context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RequestIDFrom 获取Context中的request id，没有时返回空字符串
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// NewRequestID 生成一个随机的request id(32位十六进制字符串)
+func NewRequestID() string {
+	return hex.EncodeToString(randomKey(16))
+}
+
+// RequestID 中间件，为每个请求确定request id
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID 只接受长度合适的可见ASCII字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * AccessLog 访问日志中间件，请求处理完后输出一行：
+ * request_id method path status bytes duration
+ * 需要放在RequestID之后才能拿到request id
+ */
+func AccessLog(logger *log.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			id := RequestIDFrom(r.Context())
+			if id == "" {
+				id = "-"
+			}
+			logger.Printf("%s %s %s %d %d %s", id, r.Method, r.URL.RequestURI(), rec.status, rec.bytes, time.Since(start))
+		})
+	}
+}
+
+// statusRecorder 记录handler写入的状态码和字节数
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap 让http.ResponseController可以访问底层的ResponseWriter
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// RequestIDTransport 把请求Context中的request id传递给下游服务，请求中已经设置了Header时不覆盖
+type RequestIDTransport struct {
+	Base http.RoundTripper // 默认http.DefaultTransport
+}
+
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id := RequestIDFrom(req.Context()); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+	return base.RoundTrip(req)
+}
+
+// Chain 依次用中间件包装h，第一个中间件在最外层，最先处理请求
+func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFrom(r.Context())
+	}))
+	cases := []struct {
+		name, in string
+		keep     bool
+	}{
+		{"accept", "abc-123", true},
+		{"generate", "", false},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), false},
+		{"control chars", "abc\nforged log line", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.in != "" {
+			req.Header.Set(RequestIDHeader, c.in)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if echo := rec.Header().Get(RequestIDHeader); echo != got || got == "" {
+			t.Errorf("%s: context id %q, response id %q", c.name, got, echo)
+		}
+		if (got == c.in) != c.keep {
+			t.Errorf("%s: in %q, got %q", c.name, c.in, got)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}), RequestID, AccessLog(log.New(&buf, "", 0)))
+	req := httptest.NewRequest(http.MethodPost, "/users?x=1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if line := buf.String(); !strings.HasPrefix(line, "req-1 POST /users?x=1 201 5 ") {
+		t.Fatalf("access log: %q", line)
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	// 下游服务返回收到的request id
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(RequestIDHeader)))
+	}))
+	defer downstream.Close()
+	client := NewClient(ClientConfig{Transport: &RequestIDTransport{}})
+	// 上游服务在处理请求时调用下游
+	upstream := httptest.NewServer(RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := client.Get(r.Context(), downstream.URL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Write(resp.Body)
+	})))
+	defer upstream.Close()
+
+	ctx := WithRequestID(context.Background(), "from-client")
+	resp, err := NewClient(ClientConfig{Transport: &RequestIDTransport{}}).Get(ctx, upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "from-client" {
+		t.Fatalf("downstream got request id %q", resp.Body)
+	}
+}
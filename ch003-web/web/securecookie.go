@@ -0,0 +1,178 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+/**
+ * SecureCookie 对Cookie的值签名(HMAC-SHA256)并可选加密(AES-GCM)
+ * 普通Cookie的值保存在浏览器里，用户可以随意查看和修改，服务端不能信任它
+ * 1. 签名：服务端用只有自己知道的密钥计算HMAC，值被修改后签名对不上，Decode失败
+ * 2. 加密：设置了BlockKey时先加密再签名，用户无法看到Cookie中的内容
+ * 3. 值中带有签发时间，超过MaxAge的Cookie即使签名正确也会被拒绝，防止旧Cookie被无限期重放
+ * 4. 密钥轮换：可以同时配置多个密钥，第一个用于编码，所有密钥都可用于解码，
+ *    更换密钥时把新密钥放在最前面，等旧Cookie都过期后再移除旧密钥
+ * 5. 浏览器限制单个Cookie(名称+值)最多4096字节，编码后超出会返回ErrCookieTooLong，而不是让浏览器悄悄丢弃
+ * 签名时会把Cookie名称一起计算进去，防止把A Cookie的值复制到B Cookie中使用
+ * 名称前面带上它的长度，名称和值之间没有歧义，名称中包含任意字符都不会和别的名称+值算出相同的签名
+ */
+
+// MaxCookieSize 浏览器允许的单个Cookie的最大字节数(名称+值)
+const MaxCookieSize = 4096
+
+// maxClockSkew 允许的签发时间超前于当前时间的误差，多台服务器之间的时钟不完全一致
+const maxClockSkew = time.Minute
+
+var (
+	ErrCookieTooLong = errors.New("web: 编码后的cookie超过4096字节")
+	ErrInvalidCookie = errors.New("web: cookie签名无效或格式错误")
+	ErrCookieExpired = errors.New("web: cookie已过期")
+)
+
+// CookieKey 一组密钥
+type CookieKey struct {
+	HashKey  []byte // 签名密钥，必填，建议32或64字节
+	BlockKey []byte // 加密密钥，为nil时只签名不加密，长度必须是16、24或32字节(对应AES-128/192/256)
+}
+
+type SecureCookie struct {
+	MaxAge time.Duration // Cookie的有效期，从签发时开始计算，0表示不检查
+	keys   []cookieKey
+	now    func() time.Time
+}
+
+type cookieKey struct {
+	hashKey []byte
+	aead    cipher.AEAD // 为nil时不加密
+}
+
+// NewSecureCookie 创建编解码器，keys[0]用于编码，所有key都可用于解码
+func NewSecureCookie(maxAge time.Duration, keys ...CookieKey) (*SecureCookie, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("web: SecureCookie至少需要一个密钥")
+	}
+	sc := &SecureCookie{MaxAge: maxAge, now: time.Now}
+	for i, k := range keys {
+		if len(k.HashKey) == 0 {
+			return nil, fmt.Errorf("web: 第%d个密钥的HashKey为空", i)
+		}
+		ck := cookieKey{hashKey: k.HashKey}
+		if k.BlockKey != nil {
+			block, err := aes.NewCipher(k.BlockKey)
+			if err != nil {
+				return nil, fmt.Errorf("web: 第%d个密钥的BlockKey无效: %w", i, err)
+			}
+			if ck.aead, err = cipher.NewGCM(block); err != nil {
+				return nil, err
+			}
+		}
+		sc.keys = append(sc.keys, ck)
+	}
+	return sc, nil
+}
+
+/**
+ * Encode 编码Cookie的值，格式为base64url(body + mac)
+ * body = 签发时间(8字节) + 值，加密时body = nonce + AES-GCM(签发时间 + 值)
+ * mac = HMAC-SHA256(len(name)(4字节) + name + body)
+ */
+func (sc *SecureCookie) Encode(name, value string) (string, error) {
+	k := sc.keys[0]
+	body := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(body, uint64(sc.now().Unix()))
+	body = append(body, value...)
+	if k.aead != nil {
+		nonce := make([]byte, k.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		body = k.aead.Seal(nonce, nonce, body, []byte(name))
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(append(body, cookieMAC(k.hashKey, name, body)...))
+	if size := len(name) + len(encoded); size > MaxCookieSize {
+		return "", fmt.Errorf("%w: %s编码后为%d字节", ErrCookieTooLong, name, size)
+	}
+	return encoded, nil
+}
+
+// Decode 校验并解码Cookie的值，依次尝试所有密钥
+func (sc *SecureCookie) Decode(name, encoded string) (string, error) {
+	if len(name)+len(encoded) > MaxCookieSize {
+		return "", ErrCookieTooLong
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < sha256.Size {
+		return "", ErrInvalidCookie
+	}
+	body, mac := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	for _, k := range sc.keys {
+		if !hmac.Equal(mac, cookieMAC(k.hashKey, name, body)) {
+			continue
+		}
+		plain := body
+		if k.aead != nil {
+			n := k.aead.NonceSize()
+			if len(body) < n {
+				return "", ErrInvalidCookie
+			}
+			if plain, err = k.aead.Open(nil, body[:n], body[n:], []byte(name)); err != nil {
+				return "", ErrInvalidCookie
+			}
+		}
+		if len(plain) < 8 {
+			return "", ErrInvalidCookie
+		}
+		issued := time.Unix(int64(binary.BigEndian.Uint64(plain)), 0)
+		// 签发时间不可能在未来，超出时钟误差说明Cookie不是由我们签发的
+		if issued.After(sc.now().Add(maxClockSkew)) {
+			return "", fmt.Errorf("%w: 签发时间%s晚于当前时间", ErrInvalidCookie, issued.Format(time.RFC3339))
+		}
+		if sc.MaxAge > 0 && sc.now().Sub(issued) > sc.MaxAge {
+			return "", fmt.Errorf("%w: 签发于%s", ErrCookieExpired, issued.Format(time.RFC3339))
+		}
+		return string(plain[8:]), nil
+	}
+	return "", ErrInvalidCookie
+}
+
+// SetCookie 编码c.Value后设置Cookie，c.MaxAge为0时使用SecureCookie的MaxAge
+func (sc *SecureCookie) SetCookie(w http.ResponseWriter, c *http.Cookie) error {
+	encoded, err := sc.Encode(c.Name, c.Value)
+	if err != nil {
+		return err
+	}
+	cp := *c
+	cp.Value = encoded
+	if cp.MaxAge == 0 && sc.MaxAge > 0 {
+		cp.MaxAge = int(sc.MaxAge / time.Second)
+	}
+	http.SetCookie(w, &cp)
+	return nil
+}
+
+// Cookie 读取并解码请求中的Cookie，Cookie不存在时返回http.ErrNoCookie
+func (sc *SecureCookie) Cookie(r *http.Request, name string) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return sc.Decode(name, c.Value)
+}
+
+func cookieMAC(key []byte, name string, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(name))))
+	mac.Write([]byte(name))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSecureCookie(t *testing.T, maxAge time.Duration, keys ...CookieKey) *SecureCookie {
+	t.Helper()
+	sc, err := NewSecureCookie(maxAge, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestSecureCookieRoundTrip(t *testing.T) {
+	signOnly := CookieKey{HashKey: []byte(strings.Repeat("h", 32))}
+	encrypted := CookieKey{HashKey: []byte(strings.Repeat("h", 32)), BlockKey: []byte(strings.Repeat("b", 32))}
+	for name, key := range map[string]CookieKey{"sign": signOnly, "encrypt": encrypted} {
+		t.Run(name, func(t *testing.T) {
+			sc := newTestSecureCookie(t, time.Hour, key)
+			encoded, err := sc.Encode("test_cookie", "Go-Web123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.BlockKey != nil && strings.Contains(encoded, "Go-Web") {
+				t.Fatalf("encrypted value leaks plaintext: %s", encoded)
+			}
+			got, err := sc.Decode("test_cookie", encoded)
+			if err != nil || got != "Go-Web123" {
+				t.Fatalf("Decode = %q, %v", got, err)
+			}
+			// 换一个Cookie名称或者修改任意一个字符都无法解码
+			if _, err := sc.Decode("other_cookie", encoded); !errors.Is(err, ErrInvalidCookie) {
+				t.Fatalf("decode with other name: %v", err)
+			}
+			tampered := []byte(encoded)
+			tampered[len(tampered)/2] ^= 1
+			if _, err := sc.Decode("test_cookie", string(tampered)); !errors.Is(err, ErrInvalidCookie) {
+				t.Fatalf("decode tampered value: %v", err)
+			}
+		})
+	}
+}
+
+func TestSecureCookieKeyRotation(t *testing.T) {
+	oldKey := CookieKey{HashKey: []byte("old-hash-key"), BlockKey: []byte(strings.Repeat("o", 16))}
+	newKey := CookieKey{HashKey: []byte("new-hash-key"), BlockKey: []byte(strings.Repeat("n", 16))}
+	encoded, err := newTestSecureCookie(t, 0, oldKey).Encode("c", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 轮换期间新旧密钥同时有效
+	if got, err := newTestSecureCookie(t, 0, newKey, oldKey).Decode("c", encoded); err != nil || got != "v" {
+		t.Fatalf("Decode with rotated keys = %q, %v", got, err)
+	}
+	// 移除旧密钥后旧Cookie失效
+	if _, err := newTestSecureCookie(t, 0, newKey).Decode("c", encoded); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("Decode after removing old key: %v", err)
+	}
+}
+
+func TestSecureCookieMaxAge(t *testing.T) {
+	sc := newTestSecureCookie(t, time.Hour, CookieKey{HashKey: []byte("k")})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+	encoded, err := sc.Encode("c", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(59 * time.Minute)
+	if _, err := sc.Decode("c", encoded); err != nil {
+		t.Fatalf("fresh cookie rejected: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := sc.Decode("c", encoded); !errors.Is(err, ErrCookieExpired) {
+		t.Fatalf("stale cookie accepted: %v", err)
+	}
+}
+
+func TestSecureCookieIssuedInFuture(t *testing.T) {
+	sc := newTestSecureCookie(t, time.Hour, CookieKey{HashKey: []byte("k")})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now.Add(30 * time.Second) }
+	withinSkew, err := sc.Encode("c", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.now = func() time.Time { return now.Add(2 * time.Minute) }
+	future, err := sc.Encode("c", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.now = func() time.Time { return now }
+	if _, err := sc.Decode("c", withinSkew); err != nil {
+		t.Fatalf("cookie within clock skew rejected: %v", err)
+	}
+	if _, err := sc.Decode("c", future); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("cookie issued in the future accepted: %v", err)
+	}
+}
+
+func TestSecureCookieNameBoundary(t *testing.T) {
+	sc := newTestSecureCookie(t, time.Hour, CookieKey{HashKey: []byte("k")})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+	issued := binary.BigEndian.AppendUint64(nil, uint64(now.Unix()))
+	encoded, err := sc.Encode("p", "x|"+string(issued)+"admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 把名称和body的分界移到值中的"|"处：名称为"p|<签发时间>x"，body为"<签发时间>admin"
+	// 如果签名的是name + "|" + body，两者的签名输入完全相同
+	raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+	forgedName := "p|" + string(raw[:8]) + "x"
+	forged := base64.RawURLEncoding.EncodeToString(raw[10:])
+	if v, err := sc.Decode(forgedName, forged); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("forged cookie decoded as %q, %v", v, err)
+	}
+}
+
+func TestSecureCookieTooLong(t *testing.T) {
+	sc := newTestSecureCookie(t, 0, CookieKey{HashKey: []byte("k")})
+	if _, err := sc.Encode("c", strings.Repeat("x", 3100)); !errors.Is(err, ErrCookieTooLong) {
+		t.Fatalf("Encode 3100 bytes: %v", err)
+	}
+	if _, err := sc.Encode("c", strings.Repeat("x", 2000)); err != nil {
+		t.Fatalf("Encode 2000 bytes: %v", err)
+	}
+}
+
+func TestNewSecureCookieInvalidKey(t *testing.T) {
+	if _, err := NewSecureCookie(0); err == nil {
+		t.Fatal("no keys accepted")
+	}
+	if _, err := NewSecureCookie(0, CookieKey{HashKey: []byte("k"), BlockKey: []byte("short")}); err == nil {
+		t.Fatal("invalid block key accepted")
+	}
+}
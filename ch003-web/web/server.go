@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+ * Server 管理HTTP服务的生命周期
+ * 1. 监听地址和超时时间可以通过命令行参数或环境变量配置，命令行参数优先
+ * 2. 设置Read/Write/Idle超时，防止慢速客户端一直占用连接
+ * 3. 先调用net.Listen再开始服务，端口被占用等启动错误会直接返回给调用方，而不是被忽略
+ * 4. ctx取消(通常是收到SIGINT/SIGTERM)后调用http.Server.Shutdown：不再接受新连接，等待处理中的请求完成，
+ *    超过ShutdownTimeout还没有完成时强制关闭
+ */
+
+// ServerConfig 服务配置
+type ServerConfig struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration // 读取请求头的超时时间
+	ReadTimeout       time.Duration // 读取整个请求(包括body)的超时时间
+	WriteTimeout      time.Duration // 从读完请求头到写完响应的超时时间
+	IdleTimeout       time.Duration // keep-alive连接空闲的超时时间
+	ShutdownTimeout   time.Duration // 优雅关闭时等待处理中的请求完成的最长时间
+	Debug             bool          // 是否开启/debug/下的调试接口，生产环境不要开启
+}
+
+// DefaultServerConfig 默认配置，监听:8085
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:              ":8085",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   10 * time.Second,
+	}
+}
+
+// serverEnv 环境变量名和对应的配置项
+func (cfg *ServerConfig) serverEnv() map[string]*time.Duration {
+	return map[string]*time.Duration{
+		"WEB_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"WEB_READ_TIMEOUT":        &cfg.ReadTimeout,
+		"WEB_WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"WEB_IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"WEB_SHUTDOWN_TIMEOUT":    &cfg.ShutdownTimeout,
+	}
+}
+
+// LoadEnv 从环境变量WEB_ADDR、WEB_READ_TIMEOUT等读取配置，时间的格式和time.ParseDuration一致，例如"10s"
+func (cfg *ServerConfig) LoadEnv() error {
+	if addr := os.Getenv("WEB_ADDR"); addr != "" {
+		cfg.Addr = addr
+	}
+	if v := os.Getenv("WEB_DEBUG"); v != "" {
+		debug, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("web: 环境变量WEB_DEBUG无效: %w", err)
+		}
+		cfg.Debug = debug
+	}
+	for name, d := range cfg.serverEnv() {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("web: 环境变量%s无效: %w", name, err)
+		}
+		*d = parsed
+	}
+	return nil
+}
+
+// RegisterFlags 注册命令行参数，参数的默认值为cfg当前的值，所以应在LoadEnv之后调用
+func (cfg *ServerConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "监听地址(环境变量WEB_ADDR)")
+	fs.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout, "读取请求头的超时时间(WEB_READ_HEADER_TIMEOUT)")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "读取请求的超时时间(WEB_READ_TIMEOUT)")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "写响应的超时时间(WEB_WRITE_TIMEOUT)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "空闲连接的超时时间(WEB_IDLE_TIMEOUT)")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "优雅关闭的等待时间(WEB_SHUTDOWN_TIMEOUT)")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "开启/debug/下的调试接口(WEB_DEBUG)")
+}
+
+type Server struct {
+	cfg ServerConfig
+	srv *http.Server
+	ln  net.Listener
+}
+
+func NewServer(cfg ServerConfig, h http.Handler) *Server {
+	return &Server{
+		cfg: cfg,
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           h,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+}
+
+// Listen 监听端口，端口被占用时返回的error满足errors.Is(err, syscall.EADDRINUSE)
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("web: 监听%s失败: %w", s.cfg.Addr, err)
+	}
+	s.ln = ln
+	return nil
+}
+
+// Addr 返回实际监听的地址，Addr配置为":0"时可以由此得到系统分配的端口
+func (s *Server) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Serve 开始处理请求，阻塞直到ctx取消并完成优雅关闭，需要先调用Listen
+// 正常关闭时返回nil，等待超时被强制关闭时返回error
+func (s *Server) Serve(ctx context.Context) error {
+	if s.ln == nil {
+		return errors.New("web: 需要先调用Listen")
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.srv.Serve(s.ln)
+	}()
+	select {
+	case err := <-errCh:
+		// 没有调用Shutdown就退出了，说明出现了错误
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	err := s.srv.Shutdown(shutdownCtx)
+	if err != nil {
+		// 超时后还有请求没有处理完，强制关闭所有连接
+		_ = s.srv.Close()
+		err = fmt.Errorf("web: %s内没有完成优雅关闭，已强制关闭: %w", s.cfg.ShutdownTimeout, err)
+	}
+	if serveErr := <-errCh; !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return err
+}
+
+// Run 监听端口并处理请求，直到ctx取消
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	log.Printf("listening on %s", s.Addr())
+	err := s.Serve(ctx)
+	log.Printf("server stopped")
+	return err
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, cfg ServerConfig, h http.Handler) (*Server, context.CancelFunc, <-chan error) {
+	t.Helper()
+	cfg.Addr = "127.0.0.1:0"
+	s := NewServer(cfg, h)
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx) }()
+	return s, cancel, done
+}
+
+func TestServerPortInUse(t *testing.T) {
+	s, cancel, done := startServer(t, DefaultServerConfig(), http.NotFoundHandler())
+	defer func() {
+		cancel()
+		<-done
+	}()
+	cfg := DefaultServerConfig()
+	cfg.Addr = s.Addr().String()
+	err := NewServer(cfg, http.NotFoundHandler()).Run(context.Background())
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("Run on used port: %v", err)
+	}
+}
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	s, cancel, done := startServer(t, DefaultServerConfig(), h)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + s.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+	// 关闭过程中处理中的请求不受影响
+	select {
+	case err := <-done:
+		t.Fatalf("Serve returned before in-flight request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request: %q", got)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	cfg := DefaultServerConfig()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	s, cancel, done := startServer(t, cfg, h)
+	go func() {
+		if resp, err := http.Get("http://" + s.Addr().String()); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Serve: %v", err)
+	}
+}
+
+func TestServerConfigPrecedence(t *testing.T) {
+	t.Setenv("WEB_ADDR", ":9000")
+	t.Setenv("WEB_READ_TIMEOUT", "3s")
+	t.Setenv("WEB_IDLE_TIMEOUT", "7s")
+	t.Setenv("WEB_DEBUG", "true")
+	cfg := DefaultServerConfig()
+	if err := cfg.LoadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Debug {
+		t.Fatal("WEB_DEBUG没有生效")
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-addr", ":9100", "-read-timeout", "4s", "-debug=false"}); err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultServerConfig()
+	want.Addr, want.ReadTimeout, want.IdleTimeout = ":9100", 4*time.Second, 7*time.Second
+	if cfg != want {
+		t.Fatalf("config = %+v, want %+v", cfg, want)
+	}
+
+	t.Setenv("WEB_WRITE_TIMEOUT", "soon")
+	if err := cfg.LoadEnv(); err == nil {
+		t.Fatal("invalid duration accepted")
+	}
+}
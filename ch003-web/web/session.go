@@ -0,0 +1,476 @@
+package web
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go-practice/ch001-basic/errs"
+)
+
+/**
+ * 基于Cookie的会话管理
+ * HTTP是无状态的，服务端通过在Cookie中保存一个会话ID来识别同一个用户的多次请求，会话数据本身保存在服务端
+ * 1. 会话ID是随机生成的，写入Cookie时附带HMAC签名，客户端无法伪造或篡改
+ * 2. 会话数据保存在SessionStore中，内置了带TTL的内存存储和基于文件的存储
+ * 3. 空闲超时：超过IdleTimeout没有访问的会话失效；绝对超时：创建超过AbsoluteTimeout的会话无论是否活跃都失效
+ * 4. 登录等权限变化时调用Regenerate更换会话ID，防止会话固定攻击
+ * 5. Middleware在处理请求前加载会话并放入请求的Context，处理完后保存
+ */
+
+var ErrSessionNotFound = errors.New("web: session not found")
+
+// SessionData 保存在SessionStore中的会话数据
+type SessionData struct {
+	ID         string            `json:"id"`
+	Values     map[string]string `json:"values"`
+	CreatedAt  time.Time         `json:"created_at"`
+	LastAccess time.Time         `json:"last_access"`
+}
+
+// SessionStore 会话存储，实现需要是并发安全的
+type SessionStore interface {
+	// Load 加载会话，不存在或已过期时返回ErrSessionNotFound
+	Load(id string) (*SessionData, error)
+	// Save 保存会话，ttl之后过期
+	Save(data *SessionData, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// Session 当前请求的会话，可以在处理请求时启动的协程中并发使用
+type Session struct {
+	mu        sync.Mutex
+	data      SessionData
+	destroyed bool
+}
+
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.ID
+}
+
+func (s *Session) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data.Values[key]
+	return v, ok
+}
+
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Values[key] = value
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.Values, key)
+}
+
+// CreatedAt 会话的创建时间，绝对超时从这个时间开始计算
+func (s *Session) CreatedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.CreatedAt
+}
+
+type sessionCtxKey struct{}
+
+// SessionFrom 获取请求Context中的会话，没有经过SessionManager.Middleware时返回nil
+func SessionFrom(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionCtxKey{}).(*Session)
+	return s
+}
+
+// SessionConfig 会话配置，零值字段使用默认值
+type SessionConfig struct {
+	CookieName      string        // 默认"session_id"
+	Path            string        // 默认"/"
+	Domain          string        // 默认为空，即只发送给当前域名
+	Secure          bool          // 是否只通过HTTPS发送
+	SameSite        http.SameSite // 默认http.SameSiteLaxMode
+	IdleTimeout     time.Duration // 空闲超时，默认30分钟
+	AbsoluteTimeout time.Duration // 绝对超时，默认24小时
+}
+
+type SessionManager struct {
+	store SessionStore
+	key   []byte
+	cfg   SessionConfig
+	now   func() time.Time
+}
+
+// NewSessionManager 创建会话管理器，key用于签名会话ID，长度至少32字节
+func NewSessionManager(store SessionStore, key []byte, cfg SessionConfig) *SessionManager {
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session_id"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 24 * time.Hour
+	}
+	return &SessionManager{store: store, key: key, cfg: cfg, now: time.Now}
+}
+
+// Middleware 加载(或新建)会话放入请求的Context，请求处理完后保存会话
+func (m *SessionManager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, isNew := m.load(r)
+		if isNew {
+			// Cookie必须在写响应body之前设置，所以新会话在处理请求之前就写入Cookie
+			m.setCookie(w, s.data.ID)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionCtxKey{}, s)))
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.destroyed {
+			return
+		}
+		s.data.LastAccess = m.now()
+		// 会话在空闲超时和绝对超时中较早的那个时间点过期
+		ttl := m.cfg.IdleTimeout
+		if remain := s.data.CreatedAt.Add(m.cfg.AbsoluteTimeout).Sub(s.data.LastAccess); remain < ttl {
+			ttl = remain
+		}
+		if err := m.store.Save(&s.data, ttl); err != nil {
+			// 响应已经写出，无法再返回500，只能记录日志，否则磁盘满等问题会让用户悄无声息地掉线
+			logError(r, RequestIDFrom(r.Context()), errs.Wrap(err, errs.Internal, "save session"))
+		}
+	})
+}
+
+/**
+ * Regenerate 更换会话ID并保留会话数据，应在登录成功后、写响应body之前调用
+ * CreatedAt保持不变，反复登录不会推迟绝对超时
+ * 会话中的CSRF token同时作废，当前请求Context中的token(CSRFToken)也随之失效，下一次请求时会生成新的token
+ */
+func (m *SessionManager) Regenerate(w http.ResponseWriter, r *http.Request) error {
+	s := SessionFrom(r.Context())
+	if s == nil {
+		return ErrSessionNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := m.store.Delete(s.data.ID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	s.data.ID = newSessionID()
+	// 登录前拿到的CSRF token不能在登录后继续使用
+	delete(s.data.Values, csrfSessionKey)
+	m.setCookie(w, s.data.ID)
+	return nil
+}
+
+// Destroy 销毁会话并删除Cookie，用于退出登录
+func (m *SessionManager) Destroy(w http.ResponseWriter, r *http.Request) error {
+	s := SessionFrom(r.Context())
+	if s == nil {
+		return ErrSessionNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.destroyed = true
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Path:     m.cfg.Path,
+		Domain:   m.cfg.Domain,
+		MaxAge:   -1,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+	if err := m.store.Delete(s.data.ID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// load 从Cookie中加载会话，Cookie无效、会话不存在或已超时都会新建一个会话
+func (m *SessionManager) load(r *http.Request) (s *Session, isNew bool) {
+	if c, err := r.Cookie(m.cfg.CookieName); err == nil {
+		if id, ok := m.verify(c.Value); ok {
+			if data, err := m.store.Load(id); err == nil {
+				now := m.now()
+				if now.Sub(data.LastAccess) <= m.cfg.IdleTimeout && now.Sub(data.CreatedAt) <= m.cfg.AbsoluteTimeout {
+					if data.Values == nil {
+						data.Values = make(map[string]string)
+					}
+					return &Session{data: *data}, false
+				}
+				// 删除失败不影响本次请求(总是新建会话)，但过期的会话会残留在存储中，需要记录下来
+				if err := m.store.Delete(id); err != nil && !errors.Is(err, ErrSessionNotFound) {
+					logError(r, RequestIDFrom(r.Context()), errs.Wrap(err, errs.Internal, "delete expired session"))
+				}
+			}
+		}
+	}
+	now := m.now()
+	return &Session{data: SessionData{
+		ID:         newSessionID(),
+		Values:     make(map[string]string),
+		CreatedAt:  now,
+		LastAccess: now,
+	}}, true
+}
+
+func (m *SessionManager) setCookie(w http.ResponseWriter, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    m.sign(id),
+		Path:     m.cfg.Path,
+		Domain:   m.cfg.Domain,
+		MaxAge:   int(m.cfg.AbsoluteTimeout / time.Second),
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+}
+
+// sign 生成"会话ID.签名"形式的Cookie值
+func (m *SessionManager) sign(id string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *SessionManager) verify(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || !validSessionID(id) {
+		return "", false
+	}
+	// hmac.Equal是常数时间比较，避免通过响应时间猜测签名
+	return id, hmac.Equal([]byte(value), []byte(m.sign(id)))
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validSessionID 会话ID是64位十六进制字符串，FileStore用它作为文件名，必须校验以防路径穿越
+func validSessionID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+/**
+ * MemoryStore 内存会话存储
+ * 过期的会话在Load时不会返回，同时后台协程每隔cleanupInterval清理一次，避免内存一直增长
+ * 不再使用时调用Close停止后台协程
+ */
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+	now      func() time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type memoryEntry struct {
+	data      SessionData
+	expiresAt time.Time
+}
+
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		sessions: make(map[string]memoryEntry),
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	go s.cleanupLoop(cleanupInterval)
+	return s
+}
+
+func (s *MemoryStore) Load(id string) (*SessionData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || !s.now().Before(e.expiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	data := e.data
+	// 复制一份Values，调用方修改时不影响存储中的数据
+	data.Values = make(map[string]string, len(e.data.Values))
+	for k, v := range e.data.Values {
+		data.Values[k] = v
+	}
+	return &data, nil
+}
+
+func (s *MemoryStore) Save(data *SessionData, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *data
+	cp.Values = make(map[string]string, len(data.Values))
+	for k, v := range data.Values {
+		cp.Values[k] = v
+	}
+	s.sessions[data.ID] = memoryEntry{data: cp, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Len 返回存储中的会话数量(包括已过期但还没有被清理的)
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func (s *MemoryStore) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryStore) cleanupLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+func (s *MemoryStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, e := range s.sessions {
+		if !now.Before(e.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+/**
+ * FileStore 文件会话存储，每个会话保存为Dir下的一个JSON文件，服务重启后会话依然有效
+ * 过期的文件在Load时删除，也可以定期调用Cleanup清理
+ */
+type FileStore struct {
+	Dir string
+	now func() time.Time
+}
+
+type fileEntry struct {
+	SessionData
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir, now: time.Now}, nil
+}
+
+func (s *FileStore) Load(id string) (*SessionData, error) {
+	if !validSessionID(id) {
+		return nil, ErrSessionNotFound
+	}
+	b, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e fileEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if !s.now().Before(e.ExpiresAt) {
+		_ = os.Remove(s.path(id))
+		return nil, ErrSessionNotFound
+	}
+	return &e.SessionData, nil
+}
+
+func (s *FileStore) Save(data *SessionData, ttl time.Duration) error {
+	if !validSessionID(data.ID) {
+		return errors.New("web: invalid session id")
+	}
+	b, err := json.Marshal(fileEntry{SessionData: *data, ExpiresAt: s.now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免并发请求读到写了一半的文件
+	tmp, err := os.CreateTemp(s.Dir, data.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(data.ID))
+}
+
+func (s *FileStore) Delete(id string) error {
+	if !validSessionID(id) {
+		return ErrSessionNotFound
+	}
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// Cleanup 删除所有已过期的会话文件
+func (s *FileStore) Cleanup() error {
+	matches, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, name := range matches {
+		// Load会删除过期的文件
+		_, _ = s.Load(strings.TrimSuffix(filepath.Base(name), ".json"))
+	}
+	return nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sessionTestServer 每次请求都把visits加1，/login时更换会话ID
+func sessionTestServer(t *testing.T, store SessionStore, now *time.Time) (*SessionManager, http.Handler) {
+	t.Helper()
+	m := NewSessionManager(store, []byte(strings.Repeat("k", 32)), SessionConfig{
+		IdleTimeout:     10 * time.Minute,
+		AbsoluteTimeout: time.Hour,
+	})
+	m.now = func() time.Time { return *now }
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := SessionFrom(r.Context())
+		if r.URL.Path == "/login" {
+			if err := m.Regenerate(w, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.Set("visits", valueOr(s, "visits", "")+"x")
+		w.Write([]byte(valueOr(s, "visits", "")))
+	}))
+	return m, h
+}
+
+// do 发起请求，cookie不为nil时带上，返回响应body和新设置的会话Cookie(没有时返回传入的cookie)
+func do(h http.Handler, path string, cookie *http.Cookie) (string, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" {
+			cookie = c
+		}
+	}
+	return rec.Body.String(), cookie
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	m, h := sessionTestServer(t, store, &now)
+	store.now = m.now
+
+	body, c := do(h, "/", nil)
+	if body != "x" || c == nil {
+		t.Fatalf("first visit: body=%q cookie=%v", body, c)
+	}
+	if body, _ = do(h, "/", c); body != "xx" {
+		t.Fatalf("second visit: body=%q", body)
+	}
+
+	// 篡改签名或者会话ID都会得到新会话
+	forged := *c
+	forged.Value = strings.Repeat("0", 64) + c.Value[64:]
+	if body, _ = do(h, "/", &forged); body != "x" {
+		t.Fatalf("forged cookie accepted: body=%q", body)
+	}
+
+	// Regenerate后旧的会话ID失效，数据迁移到新ID
+	body, nc := do(h, "/login", c)
+	if body != "xxx" || nc.Value == c.Value {
+		t.Fatalf("login: body=%q, id changed=%v", body, nc.Value != c.Value)
+	}
+	if body, _ = do(h, "/", c); body != "x" {
+		t.Fatalf("old id still valid after regenerate: body=%q", body)
+	}
+
+	// 空闲超时
+	now = now.Add(11 * time.Minute)
+	if body, _ = do(h, "/", nc); body != "x" {
+		t.Fatalf("idle session not expired: body=%q", body)
+	}
+}
+
+func TestSessionAbsoluteTimeout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, h := sessionTestServer(t, store, &now)
+	store.now = m.now
+
+	_, c := do(h, "/", nil)
+	// 一直保持活跃，但超过一小时后仍然过期
+	for i := 0; i < 6; i++ {
+		now = now.Add(9 * time.Minute)
+		do(h, "/", c)
+	}
+	body, _ := do(h, "/", c)
+	if body != "xxxxxxxx" {
+		t.Fatalf("active session lost: body=%q", body)
+	}
+	now = now.Add(9 * time.Minute)
+	if body, _ = do(h, "/", c); body != "x" {
+		t.Fatalf("session outlived absolute timeout: body=%q", body)
+	}
+}
+
+func TestSessionDestroy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	m, _ := sessionTestServer(t, store, &now)
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := m.Destroy(w, r); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	_, c := do(h, "/", nil)
+	if c.MaxAge >= 0 {
+		t.Fatalf("cookie not deleted: %v", c)
+	}
+	if store.Len() != 0 {
+		t.Fatalf("store has %d sessions after destroy", store.Len())
+	}
+}
+
+func TestSessionRegenerateKeepsCreatedAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	m, h := sessionTestServer(t, store, &now)
+	store.now = m.now
+
+	_, c := do(h, "/", nil)
+	// 反复登录不会推迟绝对超时
+	for i := 0; i < 6; i++ {
+		now = now.Add(9 * time.Minute)
+		_, c = do(h, "/login", c)
+	}
+	now = now.Add(9 * time.Minute)
+	if body, _ := do(h, "/", c); body != "x" {
+		t.Fatalf("regenerate extended absolute timeout: body=%q", body)
+	}
+}
+
+func TestSessionRegenerateRotatesCSRFToken(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	m := NewSessionManager(store, []byte(strings.Repeat("k", 32)), SessionConfig{})
+	h := m.Middleware(CSRF(CSRFConfig{Mode: SynchronizerToken})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			if err := m.Regenerate(w, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	u, _ := url.Parse(srv.URL)
+	csrfCookie := func() string {
+		for _, c := range jar.Cookies(u) {
+			if c.Name == "csrf_token" {
+				return c.Value
+			}
+		}
+		return ""
+	}
+	post := func(path, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		req.Header.Set("X-CSRF-Token", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	get := func() {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	before := csrfCookie()
+	if code := post("/login", before); code != http.StatusOK {
+		t.Fatalf("login: %d", code)
+	}
+	// 登录前的token在登录后无效
+	if code := post("/", before); code != http.StatusForbidden {
+		t.Fatalf("POST with pre-login token: %d", code)
+	}
+	get()
+	after := csrfCookie()
+	if after == before || post("/", after) != http.StatusOK {
+		t.Fatalf("token not rotated: before=%q after=%q", before, after)
+	}
+}
+
+// failingStore Save和Delete总是失败，模拟磁盘满或没有权限
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) Save(*SessionData, time.Duration) error { return errors.New("disk full") }
+func (failingStore) Delete(string) error                    { return errors.New("permission denied") }
+
+func TestSessionSaveErrorLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	store := NewMemoryStore(time.Hour)
+	defer store.Close()
+	m := NewSessionManager(failingStore{store}, []byte(strings.Repeat("k", 32)), SessionConfig{})
+	h := RequestID(m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if out := buf.String(); !strings.Contains(out, "save session: disk full") {
+		t.Fatalf("save error not logged: %q", out)
+	}
+}
@@ -1 +1,3 @@
-module "go-practice"
\ No newline at end of file
+module "go-practice"
+
+go 1.23
@@ -0,0 +1,26 @@
+/**
+ * randseed包解析各章节命令行使用的随机数种子
+ * 各章节只依赖这个小包，而不是依赖其他章节的代码，每个章节仍然可以单独阅读和运行
+ */
+package randseed
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Env 指定随机数种子的环境变量，命令行的-seed参数优先
+const Env = "GO_PRACTICE_SEED"
+
+// Parse 解析十进制的随机数种子，s为空时使用当前时间，generated为true，调用方可以把种子打印出来方便复现
+func Parse(s string) (seed int64, generated bool, err error) {
+	if s == "" {
+		return time.Now().UnixNano(), true, nil
+	}
+	seed, err = strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("无效的种子%q: %v", s, err)
+	}
+	return seed, false, nil
+}
@@ -0,0 +1,15 @@
+package randseed
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if seed, generated, err := Parse("42"); seed != 42 || generated || err != nil {
+		t.Fatalf("Parse(42) = %d, %v, %v", seed, generated, err)
+	}
+	if _, generated, err := Parse(""); !generated || err != nil {
+		t.Fatalf("Parse(\"\") = %v, %v", generated, err)
+	}
+	if _, _, err := Parse("abc"); err == nil {
+		t.Fatal("无效的种子没有返回错误")
+	}
+}